@@ -4,19 +4,37 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/rddl-network/rddl-2-plmnt-service/types"
 )
 
 type IR2PClient interface {
 	GetReceiveAddress(ctx context.Context, plmntAddress string) (res types.ReceiveAddressResponse, err error)
+	GetReceiveAddressWithOptions(ctx context.Context, plmntAddress string, opts ReceiveAddressOptions) (res types.ReceiveAddressResponse, err error)
+	GetConversion(ctx context.Context, liquidAddress string) (res types.ConversionResponse, err error)
+	GetArchivedConversions(ctx context.Context, from int64, to int64) (res []types.ConversionResponse, err error)
+	GetRemainders(ctx context.Context) (res []types.Remainder, err error)
+	Reconcile(ctx context.Context, from int64, to int64) (res types.ReconciliationReport, err error)
+	GetLatestReconciliation(ctx context.Context) (res types.ReconciliationReport, err error)
+	ExportConversions(ctx context.Context, from int64, to int64, format string, w io.Writer) (err error)
+}
+
+// ReceiveAddressOptions are the optional parameters of a receive address request. Zero values are omitted.
+type ReceiveAddressOptions struct {
+	RefundAddress string
+	TTL           time.Duration
 }
 
 type R2PClient struct {
-	baseURL string
-	client  *http.Client
+	baseURL    string
+	client     *http.Client
+	adminToken string
 }
 
 func NewR2PClient(baseURL string, client *http.Client) *R2PClient {
@@ -29,42 +47,122 @@ func NewR2PClient(baseURL string, client *http.Client) *R2PClient {
 	}
 }
 
+// WithAdminToken returns a client that authenticates its requests with the admin-token of the service,
+// as required by the admin endpoints.
+func (r2pc *R2PClient) WithAdminToken(adminToken string) *R2PClient {
+	c := *r2pc
+	c.adminToken = adminToken
+	return &c
+}
+
 func (r2pc *R2PClient) GetReceiveAddress(ctx context.Context, plmntAddress string) (res types.ReceiveAddressResponse, err error) {
 	err = r2pc.doRequest(ctx, http.MethodGet, r2pc.baseURL+"/receiveaddress/"+plmntAddress, nil, &res)
 	return
 }
 
+func (r2pc *R2PClient) GetReceiveAddressWithOptions(ctx context.Context, plmntAddress string, opts ReceiveAddressOptions) (res types.ReceiveAddressResponse, err error) {
+	query := url.Values{}
+	if opts.RefundAddress != "" {
+		query.Set("refundaddress", opts.RefundAddress)
+	}
+	if opts.TTL != 0 {
+		query.Set("ttl", opts.TTL.String())
+	}
+	err = r2pc.doRequest(ctx, http.MethodGet, r2pc.baseURL+"/receiveaddress/"+plmntAddress+"?"+query.Encode(), nil, &res)
+	return
+}
+
+func (r2pc *R2PClient) GetConversion(ctx context.Context, liquidAddress string) (res types.ConversionResponse, err error) {
+	err = r2pc.doRequest(ctx, http.MethodGet, r2pc.baseURL+"/conversion/"+liquidAddress, nil, &res)
+	return
+}
+
+// GetArchivedConversions returns the conversions finished within [from, to]. It needs the admin token.
+func (r2pc *R2PClient) GetArchivedConversions(ctx context.Context, from int64, to int64) (res []types.ConversionResponse, err error) {
+	err = r2pc.doRequest(ctx, http.MethodGet, fmt.Sprintf("%s/admin/archive?from=%d&to=%d", r2pc.baseURL, from, to), nil, &res)
+	return
+}
+
+// GetRemainders returns the remainder ledger of all beneficiaries. It needs the admin token.
+func (r2pc *R2PClient) GetRemainders(ctx context.Context) (res []types.Remainder, err error) {
+	err = r2pc.doRequest(ctx, http.MethodGet, r2pc.baseURL+"/admin/remainders", nil, &res)
+	return
+}
+
+// Reconcile reconciles the receipts of the service wallet within [from, to] with the mints on Planetmint.
+// It needs the admin token.
+func (r2pc *R2PClient) Reconcile(ctx context.Context, from int64, to int64) (res types.ReconciliationReport, err error) {
+	err = r2pc.doRequest(ctx, http.MethodGet, fmt.Sprintf("%s/admin/reconciliation?from=%d&to=%d", r2pc.baseURL, from, to), nil, &res)
+	return
+}
+
+// GetLatestReconciliation returns the report of the last periodic reconciliation. It needs the admin token.
+func (r2pc *R2PClient) GetLatestReconciliation(ctx context.Context) (res types.ReconciliationReport, err error) {
+	err = r2pc.doRequest(ctx, http.MethodGet, r2pc.baseURL+"/admin/reconciliation/latest", nil, &res)
+	return
+}
+
+// ExportConversions streams the deposits of the conversions completed within [from, to] to w in the
+// given format, types.ExportFormatCSV or types.ExportFormatJSON. It needs the admin token.
+func (r2pc *R2PClient) ExportConversions(ctx context.Context, from int64, to int64, format string, w io.Writer) (err error) {
+	query := url.Values{}
+	query.Set("from", strconv.FormatInt(from, 10))
+	query.Set("to", strconv.FormatInt(to, 10))
+	query.Set("format", format)
+	resp, err := r2pc.send(ctx, http.MethodGet, r2pc.baseURL+"/admin/conversions/export?"+query.Encode(), nil)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return
+}
+
 func (r2pc *R2PClient) doRequest(ctx context.Context, method, url string, body interface{}, response interface{}) (err error) {
+	resp, err := r2pc.send(ctx, method, url, body)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if response != nil {
+		return json.NewDecoder(resp.Body).Decode(response)
+	}
+
+	return
+}
+
+// send sends the request and returns the response if it succeeded, the caller closes its body.
+func (r2pc *R2PClient) send(ctx context.Context, method, url string, body interface{}) (resp *http.Response, err error) {
 	var bodyReader io.Reader
 	if body != nil {
 		bodyBytes, err := json.Marshal(body)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		bodyReader = bytes.NewBuffer(bodyBytes)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
-		return err
+		return
 	}
 
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	if r2pc.adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r2pc.adminToken)
+	}
 
-	resp, err := r2pc.client.Do(req)
+	resp, err = r2pc.client.Do(req)
 	if err != nil {
-		return err
+		return
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return &httpError{StatusCode: resp.StatusCode}
-	}
-
-	if response != nil {
-		return json.NewDecoder(resp.Body).Decode(response)
+		resp.Body.Close()
+		return nil, &httpError{StatusCode: resp.StatusCode}
 	}
 
 	return
@@ -1,11 +1,13 @@
 package client_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/rddl-network/rddl-2-plmnt-service/client"
 	"github.com/rddl-network/rddl-2-plmnt-service/types"
@@ -40,3 +42,242 @@ func TestGetReceiveAddress(t *testing.T) {
 	assert.Equal(t, expectedRes.PlanetmintBeneficiary, res.PlanetmintBeneficiary)
 	assert.Equal(t, expectedRes.LiquidAddress, res.LiquidAddress)
 }
+
+func TestGetReceiveAddressWithRefundAddress(t *testing.T) {
+	t.Parallel()
+
+	expectedRes := types.ReceiveAddressResponse{
+		LiquidAddress:         "liquidAddress",
+		PlanetmintBeneficiary: "plmntAddress",
+		RefundAddress:         "refundAddress",
+	}
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/receiveaddress/"+expectedRes.PlanetmintBeneficiary, r.URL.Path)
+		assert.Equal(t, expectedRes.RefundAddress, r.URL.Query().Get("refundaddress"))
+		assert.Equal(t, http.MethodGet, r.Method)
+
+		bytes, err := json.Marshal(expectedRes)
+		assert.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(bytes)
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	c := client.NewR2PClient(mockServer.URL, mockServer.Client())
+	res, err := c.GetReceiveAddressWithOptions(context.Background(), expectedRes.PlanetmintBeneficiary, client.ReceiveAddressOptions{RefundAddress: expectedRes.RefundAddress})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRes, res)
+}
+
+func TestGetReceiveAddressWithOptions(t *testing.T) {
+	t.Parallel()
+
+	expectedRes := types.ReceiveAddressResponse{
+		LiquidAddress:         "liquidAddress",
+		PlanetmintBeneficiary: "plmntAddress",
+		ExpiresAt:             1700007200,
+	}
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/receiveaddress/"+expectedRes.PlanetmintBeneficiary, r.URL.Path)
+		assert.Equal(t, "2h0m0s", r.URL.Query().Get("ttl"))
+		assert.False(t, r.URL.Query().Has("refundaddress"))
+		assert.Equal(t, http.MethodGet, r.Method)
+
+		bytes, err := json.Marshal(expectedRes)
+		assert.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(bytes)
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	c := client.NewR2PClient(mockServer.URL, mockServer.Client())
+	res, err := c.GetReceiveAddressWithOptions(context.Background(), expectedRes.PlanetmintBeneficiary, client.ReceiveAddressOptions{TTL: 2 * time.Hour})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRes, res)
+}
+
+func TestGetConversion(t *testing.T) {
+	t.Parallel()
+
+	expectedRes := types.ConversionResponse{
+		LiquidAddress:         "liquidAddress",
+		PlanetmintBeneficiary: "plmntAddress",
+		State:                 types.StateFundsDetected,
+		Deposits: []types.Deposit{{
+			LiquidTxID:    "liquidTxID",
+			Confirmations: 2,
+			RDDLAmount:    200000000,
+			State:         types.StateFundsDetected,
+		}},
+	}
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/conversion/"+expectedRes.LiquidAddress, r.URL.Path)
+		assert.Equal(t, http.MethodGet, r.Method)
+
+		bytes, err := json.Marshal(expectedRes)
+		assert.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(bytes)
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	c := client.NewR2PClient(mockServer.URL, mockServer.Client())
+	res, err := c.GetConversion(context.Background(), expectedRes.LiquidAddress)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRes, res)
+}
+
+func TestGetArchivedConversions(t *testing.T) {
+	t.Parallel()
+
+	expectedRes := []types.ConversionResponse{{
+		LiquidAddress:         "liquidAddress",
+		PlanetmintBeneficiary: "plmntAddress",
+		State:                 types.StateMintConfirmed,
+		Deposits: []types.Deposit{{
+			LiquidTxID:  "liquidTxID",
+			RDDLAmount:  200000000,
+			PLMNTAmount: 200,
+			State:       types.StateMintConfirmed,
+		}},
+	}}
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/admin/archive", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "10", r.URL.Query().Get("from"))
+		assert.Equal(t, "20", r.URL.Query().Get("to"))
+		assert.Equal(t, http.MethodGet, r.Method)
+
+		bytes, err := json.Marshal(expectedRes)
+		assert.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(bytes)
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	c := client.NewR2PClient(mockServer.URL, mockServer.Client()).WithAdminToken("secret")
+	res, err := c.GetArchivedConversions(context.Background(), 10, 20)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRes, res)
+}
+
+func TestGetRemainders(t *testing.T) {
+	t.Parallel()
+
+	expectedRes := []types.Remainder{{Beneficiary: "plmntAddress", Remainder: 50000000}}
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/admin/remainders", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, http.MethodGet, r.Method)
+
+		bytes, err := json.Marshal(expectedRes)
+		assert.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(bytes)
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	c := client.NewR2PClient(mockServer.URL, mockServer.Client()).WithAdminToken("secret")
+	res, err := c.GetRemainders(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRes, res)
+}
+
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+
+	expectedRes := types.ReconciliationReport{
+		From:     1700000000,
+		To:       1700086400,
+		Receipts: 2,
+		Matched:  1,
+		Unminted: map[types.ConversionState]int{},
+		Issues: []types.ReconciliationIssue{{
+			Kind:       types.IssueUnmatchedReceipt,
+			LiquidTxID: "liquidTxID",
+			RDDLAmount: 200000000,
+		}},
+	}
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/admin/reconciliation", r.URL.Path)
+		assert.Equal(t, "1700000000", r.URL.Query().Get("from"))
+		assert.Equal(t, "1700086400", r.URL.Query().Get("to"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, http.MethodGet, r.Method)
+
+		bytes, err := json.Marshal(expectedRes)
+		assert.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(bytes)
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	c := client.NewR2PClient(mockServer.URL, mockServer.Client()).WithAdminToken("secret")
+	res, err := c.Reconcile(context.Background(), expectedRes.From, expectedRes.To)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRes, res)
+}
+
+func TestGetLatestReconciliation(t *testing.T) {
+	t.Parallel()
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/admin/reconciliation/latest", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer mockServer.Close()
+
+	c := client.NewR2PClient(mockServer.URL, mockServer.Client())
+	_, err := c.GetLatestReconciliation(context.Background())
+
+	assert.EqualError(t, err, http.StatusText(http.StatusUnauthorized))
+}
+
+func TestExportConversions(t *testing.T) {
+	t.Parallel()
+
+	export := "conversion-id,liquid-address\nabc,addr\n"
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/admin/conversions/export", r.URL.Path)
+		assert.Equal(t, "100", r.URL.Query().Get("from"))
+		assert.Equal(t, "200", r.URL.Query().Get("to"))
+		assert.Equal(t, types.ExportFormatCSV, r.URL.Query().Get("format"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "text/csv")
+		_, err := w.Write([]byte(export))
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	c := client.NewR2PClient(mockServer.URL, mockServer.Client()).WithAdminToken("secret")
+	var buf bytes.Buffer
+	err := c.ExportConversions(context.Background(), 100, 200, types.ExportFormatCSV, &buf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, export, buf.String())
+}
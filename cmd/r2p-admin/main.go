@@ -0,0 +1,188 @@
+// r2p-admin calls the admin endpoints of a running rddl-2-plmnt-service.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/client"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+)
+
+const usage = `usage: r2p-admin [-url <service url>] [-token <admin token>] <command> [flags]
+
+The admin token is read from R2P_ADMIN_TOKEN unless -token is given.
+
+commands:
+  reconcile   reconcile the wallet receipts with the mints on Planetmint
+  export      export the deposits of the completed conversions as CSV or JSON
+`
+
+// errIssues is returned by a reconciliation that found issues, r2p-admin exits with exitIssues then.
+var errIssues = errors.New("the reconciliation report lists issues")
+
+const exitIssues = 2
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command given by the arguments and returns the exit code.
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("r2p-admin", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(flags.Output(), usage) }
+	url := flags.String("url", "http://localhost:8080", "base url of the service")
+	token := flags.String("token", os.Getenv("R2P_ADMIN_TOKEN"), "admin-token of the service")
+	if err := flags.Parse(args); err != nil {
+		return exitCode(err)
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 1
+	}
+
+	c := client.NewR2PClient(*url, nil).WithAdminToken(*token)
+	command, args := flags.Arg(0), flags.Args()[1:]
+	var err error
+	if command == "reconcile" {
+		err = reconcile(ctx, c, args, stdout, stderr)
+	} else if command == "export" {
+		err = export(ctx, c, args, stdout, stderr)
+	} else {
+		err = fmt.Errorf("unknown command %s", command)
+	}
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(stderr, "r2p-admin:", err)
+	}
+	return exitCode(err)
+}
+
+// exitCode returns the exit code for the outcome of a command. Asking for help is not an error, the flag
+// package printed the usage already.
+func exitCode(err error) int {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if errors.Is(err, errIssues) {
+		return exitIssues
+	}
+	return 1
+}
+
+// reconcile prints the reconciliation report of the given range, or of the last periodic reconciliation
+// with -latest. errIssues is returned if the report lists issues.
+func reconcile(ctx context.Context, c *client.R2PClient, args []string, w io.Writer, stderr io.Writer) (err error) {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	to := flags.String("to", "", "end of the range as unix timestamp, RFC 3339 time or date (default now)")
+	from := flags.String("from", "", "start of the range as unix timestamp, RFC 3339 time or date (default 7 days before -to)")
+	latest := flags.Bool("latest", false, "print the report of the last periodic reconciliation")
+	if err = flags.Parse(args); err != nil {
+		return
+	}
+
+	var report types.ReconciliationReport
+	if *latest {
+		report, err = c.GetLatestReconciliation(ctx)
+	} else {
+		end := time.Now()
+		if *to != "" {
+			if end, err = parseTime(*to); err != nil {
+				return
+			}
+		}
+		start := end.Add(-7 * 24 * time.Hour)
+		if *from != "" {
+			if start, err = parseTime(*from); err != nil {
+				return
+			}
+		}
+		report, err = c.Reconcile(ctx, start.Unix(), end.Unix())
+	}
+	if err != nil {
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		return
+	}
+	if len(report.Issues) > 0 {
+		return errIssues
+	}
+	return
+}
+
+// export writes the deposits of the conversions completed within the given range, or the given month, to
+// stdout or the file given with -o.
+func export(ctx context.Context, c *client.R2PClient, args []string, stdout io.Writer, stderr io.Writer) (err error) {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	to := flags.String("to", "", "end of the range as unix timestamp, RFC 3339 time or date (default now)")
+	from := flags.String("from", "", "start of the range as unix timestamp, RFC 3339 time or date (default 0)")
+	month := flags.String("month", "", "export the given month in UTC, e.g. 2024-01, instead of -from and -to")
+	format := flags.String("format", types.ExportFormatCSV, "format of the export, csv or json")
+	output := flags.String("o", "", "file to write the export to (default stdout)")
+	if err = flags.Parse(args); err != nil {
+		return
+	}
+
+	var start, end time.Time
+	if *month != "" {
+		if start, err = time.Parse("2006-01", *month); err != nil {
+			return fmt.Errorf("invalid month %s, expected e.g. 2024-01", *month)
+		}
+		end = start.AddDate(0, 1, 0).Add(-time.Second)
+	} else {
+		end = time.Now()
+		if *to != "" {
+			if end, err = parseTime(*to); err != nil {
+				return
+			}
+		}
+		start = time.Unix(0, 0)
+		if *from != "" {
+			if start, err = parseTime(*from); err != nil {
+				return
+			}
+		}
+	}
+
+	w := stdout
+	if *output != "" {
+		var file *os.File
+		if file, err = os.Create(*output); err != nil {
+			return
+		}
+		defer func() {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = file
+	}
+	return c.ExportConversions(ctx, start.Unix(), end.Unix(), *format, w)
+}
+
+// parseTime parses a unix timestamp, an RFC 3339 time or a date.
+func parseTime(value string) (t time.Time, err error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	if t, err = time.Parse(time.RFC3339, value); err == nil {
+		return
+	}
+	if t, err = time.Parse(time.DateOnly, value); err == nil {
+		return
+	}
+	return t, fmt.Errorf("invalid time %s, expected a unix timestamp, an RFC 3339 time or a date", value)
+}
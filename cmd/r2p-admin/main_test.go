@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTime(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc     string
+		value    string
+		expected time.Time
+		valid    bool
+	}{
+		{desc: "unix timestamp", value: "1704067200", expected: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), valid: true},
+		{desc: "rfc 3339 time", value: "2024-01-01T12:30:00+01:00", expected: time.Date(2024, 1, 1, 11, 30, 0, 0, time.UTC), valid: true},
+		{desc: "date", value: "2024-02-01", expected: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), valid: true},
+		{desc: "invalid date", value: "2024-13-01", valid: false},
+		{desc: "word", value: "yesterday", valid: false},
+		{desc: "empty", value: "", valid: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			parsed, err := parseTime(tc.value)
+			if !tc.valid {
+				assert.ErrorContains(t, err, "invalid time")
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, tc.expected.Equal(parsed), "expected %s, got %s", tc.expected, parsed)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc   string
+		args   []string
+		code   int
+		path   string
+		query  url.Values
+		issues bool
+	}{
+		{
+			desc:  "reconcile range",
+			args:  []string{"reconcile", "-from", "2024-01-01", "-to", "1706745600"},
+			path:  "/admin/reconciliation",
+			query: url.Values{"from": {"1704067200"}, "to": {"1706745600"}},
+		},
+		{
+			desc:  "reconcile default range",
+			args:  []string{"reconcile", "-to", "2024-01-08"},
+			path:  "/admin/reconciliation",
+			query: url.Values{"from": {"1704067200"}, "to": {"1704672000"}},
+		},
+		{desc: "latest reconciliation", args: []string{"reconcile", "-latest"}, path: "/admin/reconciliation/latest", query: url.Values{}},
+		{desc: "reconciliation with issues", args: []string{"reconcile", "-latest"}, code: exitIssues, path: "/admin/reconciliation/latest", query: url.Values{}, issues: true},
+		{
+			desc:  "export month",
+			args:  []string{"export", "-month", "2024-01", "-format", types.ExportFormatJSON},
+			path:  "/admin/conversions/export",
+			query: url.Values{"from": {"1704067200"}, "to": {"1706745599"}, "format": {types.ExportFormatJSON}},
+		},
+		{
+			desc:  "export range",
+			args:  []string{"export", "-from", "1704067200", "-to", "2024-01-02T00:00:00Z"},
+			path:  "/admin/conversions/export",
+			query: url.Values{"from": {"1704067200"}, "to": {"1704153600"}, "format": {types.ExportFormatCSV}},
+		},
+		{desc: "invalid month", args: []string{"export", "-month", "january"}, code: 1},
+		{desc: "invalid time", args: []string{"reconcile", "-from", "yesterday"}, code: 1},
+		{desc: "unknown flag", args: []string{"export", "-since", "2024-01-01"}, code: 1},
+		{desc: "unknown command", args: []string{"refund"}, code: 1},
+		{desc: "no command", args: []string{}, code: 1},
+		{desc: "help", args: []string{"-h"}, code: 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			var requests []*http.Request
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+				if r.URL.Path == "/admin/conversions/export" {
+					_, _ = w.Write([]byte("export"))
+					return
+				}
+				var report types.ReconciliationReport
+				if tc.issues {
+					report.Issues = []types.ReconciliationIssue{{Kind: types.IssueUnmatchedReceipt}}
+				}
+				assert.NoError(t, json.NewEncoder(w).Encode(report))
+			}))
+			defer server.Close()
+
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"-url", server.URL, "-token", "secret"}, tc.args...)
+			code := run(context.Background(), args, &stdout, &stderr)
+			assert.Equal(t, tc.code, code, stderr.String())
+			if tc.path == "" {
+				assert.Empty(t, requests)
+				return
+			}
+			assert.Len(t, requests, 1)
+			assert.Equal(t, tc.path, requests[0].URL.Path)
+			assert.Equal(t, tc.query, requests[0].URL.Query())
+			assert.Equal(t, "Bearer secret", requests[0].Header.Get("Authorization"))
+			assert.NotEmpty(t, stdout.String())
+		})
+	}
+}
+
+func TestExportToFile(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("conversion-id,liquid-address\n"))
+	}))
+	defer server.Close()
+
+	output := filepath.Join(t.TempDir(), "conversions.csv")
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"-url", server.URL, "export", "-month", "2024-01", "-o", output}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Empty(t, stdout.String())
+	written, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Equal(t, "conversion-id,liquid-address\n", string(written))
+}
@@ -1,15 +1,19 @@
 package main
 
 import (
+	"context"
 	stdlog "log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/syndtr/goleveldb/leveldb"
 
 	"github.com/planetmint/planetmint-go/app"
 	"github.com/planetmint/planetmint-go/lib"
-	log "github.com/rddl-network/go-utils/logger"
-	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	r2pconfig "github.com/rddl-network/rddl-2-plmnt-service/config"
 	"github.com/rddl-network/rddl-2-plmnt-service/service"
 )
 
@@ -25,11 +29,20 @@ var (
 
 var libConfig *lib.Config
 
+// shutdownTimeout bounds how long open requests and running conversion passes are waited for on shutdown.
+const shutdownTimeout = 30 * time.Second
+
+// selfCheckTimeout bounds the startup checks of Elements and Planetmint.
+const selfCheckTimeout = time.Minute
+
 func main() {
-	config, err := config.LoadConfig("./")
+	config, err := r2pconfig.LoadConfig("./")
 	if err != nil {
 		stdlog.Fatalf("fatal error loading config file: %s", err)
 	}
+	if err = r2pconfig.GetConfig().Validate(); err != nil {
+		stdlog.Fatalf("invalid configuration: %s", err)
+	}
 
 	planetmintAddress = config.GetString("planetmint-address")
 	if err != nil || planetmintAddress == "" {
@@ -61,6 +74,11 @@ func main() {
 		panic("Could not read configuration")
 	}
 
+	shutdownTracing, err := service.SetupTracing(context.Background(), r2pconfig.GetConfig())
+	if err != nil {
+		stdlog.Fatalf("fatal error setting up tracing: %s", err)
+	}
+
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 
@@ -69,13 +87,51 @@ func main() {
 		db.Close()
 		stdlog.Fatal(err)
 	}
-	defer db.Close()
-	pmClient := service.NewPlanetmintClient()
+	pmClient, err := service.NewPlanetmintClient()
+	if err != nil {
+		stdlog.Fatalf("fatal error connecting to planetmint: %s", err)
+	}
 	eClient := service.NewElementsClient()
-	logger := log.GetLogger(config.GetString("log-level"))
-	service := service.NewR2PService(router, pmClient, eClient, db, logger)
+	rateProvider := service.NewRateProvider(r2pconfig.GetConfig())
+	logger, err := service.NewLogger(os.Stderr, r2pconfig.GetConfig().LogLevel, r2pconfig.GetConfig().LogFormat)
+	if err != nil {
+		stdlog.Fatalf("fatal error creating logger: %s", err)
+	}
+	service := service.NewR2PService(router, pmClient, eClient, rateProvider, db, logger)
+
+	if r2pconfig.GetConfig().SelfCheck {
+		ctx, cancel := context.WithTimeout(context.Background(), selfCheckTimeout)
+		results, err := service.SelfCheck(ctx)
+		cancel()
+		for _, result := range results {
+			stdlog.Println(result)
+		}
+		if err != nil {
+			stdlog.Fatalf("self-check failed, the service is not started")
+		}
+	}
 
-	if err = service.Run(config); err != nil {
-		stdlog.Panicf("error occurred while spinning up service: %v", err)
+	go func() {
+		if err := service.Run(config); err != nil {
+			stdlog.Panicf("error occurred while spinning up service: %v", err)
+		}
+	}()
+
+	// the database is closed by the shutdown, so it has to run on SIGINT and SIGTERM
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	logger.Info("msg", "shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err = service.Shutdown(ctx); err != nil {
+		stdlog.Printf("error occurred while shutting down service: %v", err)
+	}
+	if err = pmClient.Close(); err != nil {
+		stdlog.Printf("error occurred while closing planetmint connection: %v", err)
+	}
+	if err = shutdownTracing(ctx); err != nil {
+		stdlog.Printf("error occurred while flushing traces: %v", err)
 	}
 }
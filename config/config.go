@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"slices"
 	"sync"
+	"time"
 )
 
 const DefaultConfigTemplate = `
@@ -12,29 +14,102 @@ rpc-host="{{ .RPCHost }}"
 rpc-user="{{ .RPCUser }}"
 rpc-pass="{{ .RPCPass }}"
 planetmint-rpc-host="{{ .PlanetmintRPCHost }}"
+planetmint-tls={{ .PlanetmintTLS }}
+planetmint-tls-ca="{{ .PlanetmintTLSCA }}"
 service-bind="{{ .ServiceBind }}"
 service-port={{ .ServicePort }}
 accepted-asset="{{ .AcceptedAsset }}"
 wallet="{{ .Wallet }}"
 confirmations={{ .Confirmations }}
 log-level="{{ .LogLevel }}"
+log-format="{{ .LogFormat }}"
+archive-retention="{{ .ArchiveRetention }}"
+conversion-rate={{ .ConversionRate }}
+rate-source="{{ .RateSource }}"
+rate-cache-ttl="{{ .RateCacheTTL }}"
+rate-max-age="{{ .RateMaxAge }}"
+min-deposit={{ .MinDeposit }}
+max-deposit={{ .MaxDeposit }}
+admin-token="{{ .AdminToken }}"
+auto-refund={{ .AutoRefund }}
+convert-late-deposits={{ .ConvertLateDeposits }}
+address-ttl="{{ .AddressTTL }}"
+min-address-ttl="{{ .MinAddressTTL }}"
+max-address-ttl="{{ .MaxAddressTTL }}"
+cleanup-interval="{{ .CleanupInterval }}"
+conversion-interval="{{ .ConversionInterval }}"
+conversion-workers={{ .ConversionWorkers }}
+reconciliation-interval="{{ .ReconciliationInterval }}"
+reconciliation-window="{{ .ReconciliationWindow }}"
+rpc-timeout="{{ .RPCTimeout }}"
+mint-inclusion-timeout="{{ .MintInclusionTimeout }}"
+mint-batch-size={{ .MintBatchSize }}
+planetmint-tx-gas={{ .PlanetmintTxGas }}
+dry-run={{ .DryRun }}
+self-check={{ .SelfCheck }}
+trace-exporter="{{ .TraceExporter }}"
+otlp-endpoint="{{ .OTLPEndpoint }}"
+otlp-tls={{ .OTLPTLS }}
 `
 
 type Config struct {
-	PlanetmintAddress string `mapstructure:"planetmint-address"`
-	PlanetmintChainID string `mapstructure:"planetmint-chain-id"`
-	RPCHost           string `mapstructure:"rpc-host"`
-	RPCUser           string `mapstructure:"rpc-user"`
-	RPCPass           string `mapstructure:"rpc-pass"`
-	PlanetmintRPCHost string `mapstructure:"planetmint-rpc-host"`
-	ServicePort       int    `mapstructure:"service-port"`
-	ServiceBind       string `mapstructure:"service-bind"`
-	AcceptedAsset     string `mapstructure:"accepted-asset"`
-	Wallet            string `mapstructure:"wallet"`
-	Confirmations     int64  `mapstructure:"confirmations"`
-	LogLevel          string `mapstructure:"log-level"`
+	PlanetmintAddress      string        `mapstructure:"planetmint-address"`
+	PlanetmintChainID      string        `mapstructure:"planetmint-chain-id"`
+	RPCHost                string        `mapstructure:"rpc-host"`
+	RPCUser                string        `mapstructure:"rpc-user"`
+	RPCPass                string        `mapstructure:"rpc-pass"`
+	PlanetmintRPCHost      string        `mapstructure:"planetmint-rpc-host"`
+	PlanetmintTLS          bool          `mapstructure:"planetmint-tls"`
+	PlanetmintTLSCA        string        `mapstructure:"planetmint-tls-ca"`
+	ServicePort            int           `mapstructure:"service-port"`
+	ServiceBind            string        `mapstructure:"service-bind"`
+	AcceptedAsset          string        `mapstructure:"accepted-asset"`
+	Wallet                 string        `mapstructure:"wallet"`
+	Confirmations          int64         `mapstructure:"confirmations"`
+	LogLevel               string        `mapstructure:"log-level"`
+	LogFormat              string        `mapstructure:"log-format"`
+	ArchiveRetention       time.Duration `mapstructure:"archive-retention"`
+	ConversionRate         uint64        `mapstructure:"conversion-rate"`
+	RateSource             string        `mapstructure:"rate-source"`
+	RateCacheTTL           time.Duration `mapstructure:"rate-cache-ttl"`
+	RateMaxAge             time.Duration `mapstructure:"rate-max-age"`
+	MinDeposit             float64       `mapstructure:"min-deposit"`
+	MaxDeposit             float64       `mapstructure:"max-deposit"`
+	AdminToken             string        `mapstructure:"admin-token"`
+	AutoRefund             bool          `mapstructure:"auto-refund"`
+	ConvertLateDeposits    bool          `mapstructure:"convert-late-deposits"`
+	AddressTTL             time.Duration `mapstructure:"address-ttl"`
+	MinAddressTTL          time.Duration `mapstructure:"min-address-ttl"`
+	MaxAddressTTL          time.Duration `mapstructure:"max-address-ttl"`
+	CleanupInterval        time.Duration `mapstructure:"cleanup-interval"`
+	ConversionInterval     time.Duration `mapstructure:"conversion-interval"`
+	ConversionWorkers      int           `mapstructure:"conversion-workers"`
+	ReconciliationInterval time.Duration `mapstructure:"reconciliation-interval"`
+	ReconciliationWindow   time.Duration `mapstructure:"reconciliation-window"`
+	RPCTimeout             time.Duration `mapstructure:"rpc-timeout"`
+	MintInclusionTimeout   time.Duration `mapstructure:"mint-inclusion-timeout"`
+	MintBatchSize          int           `mapstructure:"mint-batch-size"`
+	PlanetmintTxGas        uint64        `mapstructure:"planetmint-tx-gas"`
+	DryRun                 bool          `mapstructure:"dry-run"`
+	SelfCheck              bool          `mapstructure:"self-check"`
+	TraceExporter          string        `mapstructure:"trace-exporter"`
+	OTLPEndpoint           string        `mapstructure:"otlp-endpoint"`
+	OTLPTLS                bool          `mapstructure:"otlp-tls"`
 }
 
+// Formats of the log lines selected with log-format.
+const (
+	LogFormatLogfmt = "logfmt"
+	LogFormatJSON   = "json"
+)
+
+// Exporters the traces can be sent to with trace-exporter.
+const (
+	TraceExporterNone   = "none"
+	TraceExporterOTLP   = "otlp"
+	TraceExporterStdout = "stdout"
+)
+
 // global singleton
 var (
 	config     *Config
@@ -44,18 +119,48 @@ var (
 // DefaultConfig returns RDDL-2-PLMNT default config
 func DefaultConfig() *Config {
 	return &Config{
-		PlanetmintAddress: "plmnt15xuq0yfxtd70l7jzr5hg722sxzcqqdcr8ptpl5",
-		PlanetmintChainID: "planetmint-testnet-1",
-		RPCHost:           "planetmint-go-testnet-3.rddl.io:18884",
-		RPCUser:           "user",
-		RPCPass:           "password",
-		PlanetmintRPCHost: "127.0.0.1:9090",
-		ServicePort:       8080,
-		ServiceBind:       "localhost",
-		AcceptedAsset:     "7add40beb27df701e02ee85089c5bc0021bc813823fedb5f1dcb5debda7f3da9",
-		Wallet:            "rddl2plmnt",
-		Confirmations:     10,
-		LogLevel:          "info",
+		PlanetmintAddress:      "plmnt15xuq0yfxtd70l7jzr5hg722sxzcqqdcr8ptpl5",
+		PlanetmintChainID:      "planetmint-testnet-1",
+		RPCHost:                "planetmint-go-testnet-3.rddl.io:18884",
+		RPCUser:                "user",
+		RPCPass:                "password",
+		PlanetmintRPCHost:      "127.0.0.1:9090",
+		PlanetmintTLS:          false,
+		PlanetmintTLSCA:        "",
+		ServicePort:            8080,
+		ServiceBind:            "localhost",
+		AcceptedAsset:          "7add40beb27df701e02ee85089c5bc0021bc813823fedb5f1dcb5debda7f3da9",
+		Wallet:                 "rddl2plmnt",
+		Confirmations:          10,
+		LogLevel:               "info",
+		LogFormat:              LogFormatLogfmt,
+		ArchiveRetention:       365 * 24 * time.Hour,
+		ConversionRate:         100,
+		RateSource:             "",
+		RateCacheTTL:           5 * time.Minute,
+		RateMaxAge:             time.Hour,
+		MinDeposit:             0,
+		MaxDeposit:             0,
+		AdminToken:             "",
+		AutoRefund:             false,
+		ConvertLateDeposits:    false,
+		AddressTTL:             12 * time.Hour,
+		MinAddressTTL:          time.Hour,
+		MaxAddressTTL:          48 * time.Hour,
+		CleanupInterval:        2 * time.Hour,
+		ConversionInterval:     2 * time.Minute,
+		ConversionWorkers:      8,
+		ReconciliationInterval: 24 * time.Hour,
+		ReconciliationWindow:   7 * 24 * time.Hour,
+		RPCTimeout:             30 * time.Second,
+		MintInclusionTimeout:   10 * time.Minute,
+		MintBatchSize:          1,
+		PlanetmintTxGas:        200000,
+		DryRun:                 false,
+		SelfCheck:              true,
+		TraceExporter:          TraceExporterNone,
+		OTLPEndpoint:           "localhost:4317",
+		OTLPTLS:                false,
 	}
 }
 
@@ -66,6 +171,82 @@ func GetConfig() *Config {
 	return config
 }
 
+// Validate checks the monitoring window, the task intervals, the conversion workers, the conversion rate,
+// the logging and the trace exporter.
+func (c *Config) Validate() (err error) {
+	if c.CleanupInterval <= 0 {
+		return fmt.Errorf("cleanup-interval must be positive, got %s", c.CleanupInterval)
+	}
+	// a retention of 0 would prune the whole archive with every cleanup pass
+	if c.ArchiveRetention <= 0 {
+		return fmt.Errorf("archive-retention must be positive, got %s", c.ArchiveRetention)
+	}
+	if c.ConversionInterval <= 0 {
+		return fmt.Errorf("conversion-interval must be positive, got %s", c.ConversionInterval)
+	}
+	if c.ConversionWorkers < 1 {
+		return fmt.Errorf("conversion-workers must be at least 1, got %d", c.ConversionWorkers)
+	}
+	if c.ReconciliationInterval < 0 {
+		return fmt.Errorf("reconciliation-interval must not be negative, got %s", c.ReconciliationInterval)
+	}
+	if c.ReconciliationWindow <= 0 {
+		return fmt.Errorf("reconciliation-window must be positive, got %s", c.ReconciliationWindow)
+	}
+	if c.RPCTimeout <= 0 {
+		return fmt.Errorf("rpc-timeout must be positive, got %s", c.RPCTimeout)
+	}
+	if c.MintInclusionTimeout <= 0 {
+		return fmt.Errorf("mint-inclusion-timeout must be positive, got %s", c.MintInclusionTimeout)
+	}
+	if c.MintBatchSize < 1 {
+		return fmt.Errorf("mint-batch-size must be at least 1, got %d", c.MintBatchSize)
+	}
+	if c.PlanetmintTxGas == 0 {
+		return fmt.Errorf("planetmint-tx-gas must be positive, got %d", c.PlanetmintTxGas)
+	}
+	// a static rate of 0 would credit every deposit instead of minting it
+	if c.RateSource == "" && c.ConversionRate == 0 {
+		return fmt.Errorf("conversion-rate must be positive without a rate-source, got %d", c.ConversionRate)
+	}
+	if c.RateCacheTTL <= 0 {
+		return fmt.Errorf("rate-cache-ttl must be positive, got %s", c.RateCacheTTL)
+	}
+	// a max-age of 0 would reject every rate as stale
+	if c.RateMaxAge <= 0 {
+		return fmt.Errorf("rate-max-age must be positive, got %s", c.RateMaxAge)
+	}
+	if c.MinDeposit < 0 {
+		return fmt.Errorf("min-deposit must not be negative, got %g", c.MinDeposit)
+	}
+	// a max-deposit of 0 disables the limit
+	if c.MaxDeposit < 0 {
+		return fmt.Errorf("max-deposit must not be negative, got %g", c.MaxDeposit)
+	}
+	if c.MaxDeposit > 0 && c.MinDeposit > c.MaxDeposit {
+		return fmt.Errorf("min-deposit %g must not be above max-deposit %g", c.MinDeposit, c.MaxDeposit)
+	}
+	if !slices.Contains([]string{"debug", "info", "warn", "error"}, c.LogLevel) {
+		return fmt.Errorf("log-level must be one of debug, info, warn or error, got %s", c.LogLevel)
+	}
+	if c.LogFormat != LogFormatLogfmt && c.LogFormat != LogFormatJSON {
+		return fmt.Errorf("log-format must be %s or %s, got %s", LogFormatLogfmt, LogFormatJSON, c.LogFormat)
+	}
+	if c.TraceExporter != TraceExporterNone && c.TraceExporter != TraceExporterOTLP && c.TraceExporter != TraceExporterStdout {
+		return fmt.Errorf("trace-exporter must be one of %s, %s or %s, got %s", TraceExporterNone, TraceExporterOTLP, TraceExporterStdout, c.TraceExporter)
+	}
+	if c.MinAddressTTL <= 0 {
+		return fmt.Errorf("min-address-ttl must be positive, got %s", c.MinAddressTTL)
+	}
+	if c.MaxAddressTTL < c.MinAddressTTL {
+		return fmt.Errorf("max-address-ttl %s must not be below min-address-ttl %s", c.MaxAddressTTL, c.MinAddressTTL)
+	}
+	if c.AddressTTL < c.MinAddressTTL || c.AddressTTL > c.MaxAddressTTL {
+		return fmt.Errorf("address-ttl %s must be between min-address-ttl %s and max-address-ttl %s", c.AddressTTL, c.MinAddressTTL, c.MaxAddressTTL)
+	}
+	return
+}
+
 func (c *Config) GetElementsURL() string {
 	url := fmt.Sprintf("http://%s:%s@%s/wallet/%s", c.RPCUser, c.RPCPass, c.RPCHost, c.Wallet)
 	return url
@@ -0,0 +1,64 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc   string
+		modify func(cfg *config.Config)
+		valid  bool
+	}{
+		{desc: "defaults", modify: func(_ *config.Config) {}, valid: true},
+		{desc: "no cleanup interval", modify: func(cfg *config.Config) { cfg.CleanupInterval = 0 }, valid: false},
+		{desc: "no archive retention", modify: func(cfg *config.Config) { cfg.ArchiveRetention = 0 }, valid: false},
+		{desc: "negative conversion interval", modify: func(cfg *config.Config) { cfg.ConversionInterval = -time.Minute }, valid: false},
+		{desc: "no conversion workers", modify: func(cfg *config.Config) { cfg.ConversionWorkers = 0 }, valid: false},
+		{desc: "reconciliation disabled", modify: func(cfg *config.Config) { cfg.ReconciliationInterval = 0 }, valid: true},
+		{desc: "negative reconciliation interval", modify: func(cfg *config.Config) { cfg.ReconciliationInterval = -time.Hour }, valid: false},
+		{desc: "no reconciliation window", modify: func(cfg *config.Config) { cfg.ReconciliationWindow = 0 }, valid: false},
+		{desc: "no rpc timeout", modify: func(cfg *config.Config) { cfg.RPCTimeout = 0 }, valid: false},
+		{desc: "no mint inclusion timeout", modify: func(cfg *config.Config) { cfg.MintInclusionTimeout = 0 }, valid: false},
+		{desc: "no mint batch size", modify: func(cfg *config.Config) { cfg.MintBatchSize = 0 }, valid: false},
+		{desc: "no planetmint tx gas", modify: func(cfg *config.Config) { cfg.PlanetmintTxGas = 0 }, valid: false},
+		{desc: "no conversion rate", modify: func(cfg *config.Config) { cfg.ConversionRate = 0 }, valid: false},
+		{desc: "no conversion rate with rate source", modify: func(cfg *config.Config) { cfg.ConversionRate, cfg.RateSource = 0, "https://rates.example.com" }, valid: true},
+		{desc: "no rate cache ttl", modify: func(cfg *config.Config) { cfg.RateCacheTTL = 0 }, valid: false},
+		{desc: "negative rate cache ttl", modify: func(cfg *config.Config) { cfg.RateCacheTTL = -time.Minute }, valid: false},
+		{desc: "no rate max age", modify: func(cfg *config.Config) { cfg.RateMaxAge = 0 }, valid: false},
+		{desc: "negative rate max age", modify: func(cfg *config.Config) { cfg.RateMaxAge = -time.Hour }, valid: false},
+		{desc: "negative min deposit", modify: func(cfg *config.Config) { cfg.MinDeposit = -1 }, valid: false},
+		{desc: "negative max deposit", modify: func(cfg *config.Config) { cfg.MaxDeposit = -1 }, valid: false},
+		{desc: "min deposit above max deposit", modify: func(cfg *config.Config) { cfg.MinDeposit, cfg.MaxDeposit = 2, 1 }, valid: false},
+		{desc: "min deposit without max deposit", modify: func(cfg *config.Config) { cfg.MinDeposit = 2 }, valid: true},
+		{desc: "json log format", modify: func(cfg *config.Config) { cfg.LogFormat = config.LogFormatJSON }, valid: true},
+		{desc: "unknown log format", modify: func(cfg *config.Config) { cfg.LogFormat = "text" }, valid: false},
+		{desc: "unknown log level", modify: func(cfg *config.Config) { cfg.LogLevel = "trace" }, valid: false},
+		{desc: "otlp trace exporter", modify: func(cfg *config.Config) { cfg.TraceExporter = config.TraceExporterOTLP }, valid: true},
+		{desc: "unknown trace exporter", modify: func(cfg *config.Config) { cfg.TraceExporter = "jaeger" }, valid: false},
+		{desc: "no min address ttl", modify: func(cfg *config.Config) { cfg.MinAddressTTL = 0 }, valid: false},
+		{desc: "max below min address ttl", modify: func(cfg *config.Config) { cfg.MaxAddressTTL = time.Minute }, valid: false},
+		{desc: "address ttl above max", modify: func(cfg *config.Config) { cfg.AddressTTL = 72 * time.Hour }, valid: false},
+		{desc: "address ttl below min", modify: func(cfg *config.Config) { cfg.AddressTTL = time.Minute }, valid: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			cfg := config.DefaultConfig()
+			tc.modify(cfg)
+			err := cfg.Validate()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
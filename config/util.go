@@ -17,6 +17,39 @@ func LoadConfig(path string) (v *viper.Viper, err error) {
 
 	v.AutomaticEnv()
 
+	// keys added after the initial release fall back to their defaults in existing config files
+	defaults := DefaultConfig()
+	v.SetDefault("planetmint-tls", defaults.PlanetmintTLS)
+	v.SetDefault("planetmint-tls-ca", defaults.PlanetmintTLSCA)
+	v.SetDefault("log-format", defaults.LogFormat)
+	v.SetDefault("archive-retention", defaults.ArchiveRetention)
+	v.SetDefault("conversion-rate", defaults.ConversionRate)
+	v.SetDefault("rate-source", defaults.RateSource)
+	v.SetDefault("rate-cache-ttl", defaults.RateCacheTTL)
+	v.SetDefault("rate-max-age", defaults.RateMaxAge)
+	v.SetDefault("min-deposit", defaults.MinDeposit)
+	v.SetDefault("max-deposit", defaults.MaxDeposit)
+	v.SetDefault("admin-token", defaults.AdminToken)
+	v.SetDefault("auto-refund", defaults.AutoRefund)
+	v.SetDefault("convert-late-deposits", defaults.ConvertLateDeposits)
+	v.SetDefault("address-ttl", defaults.AddressTTL)
+	v.SetDefault("min-address-ttl", defaults.MinAddressTTL)
+	v.SetDefault("max-address-ttl", defaults.MaxAddressTTL)
+	v.SetDefault("cleanup-interval", defaults.CleanupInterval)
+	v.SetDefault("conversion-interval", defaults.ConversionInterval)
+	v.SetDefault("conversion-workers", defaults.ConversionWorkers)
+	v.SetDefault("reconciliation-interval", defaults.ReconciliationInterval)
+	v.SetDefault("reconciliation-window", defaults.ReconciliationWindow)
+	v.SetDefault("rpc-timeout", defaults.RPCTimeout)
+	v.SetDefault("mint-inclusion-timeout", defaults.MintInclusionTimeout)
+	v.SetDefault("mint-batch-size", defaults.MintBatchSize)
+	v.SetDefault("planetmint-tx-gas", defaults.PlanetmintTxGas)
+	v.SetDefault("dry-run", defaults.DryRun)
+	v.SetDefault("self-check", defaults.SelfCheck)
+	v.SetDefault("trace-exporter", defaults.TraceExporter)
+	v.SetDefault("otlp-endpoint", defaults.OTLPEndpoint)
+	v.SetDefault("otlp-tls", defaults.OTLPTLS)
+
 	err = v.ReadInConfig()
 	if err == nil {
 		cfg := GetConfig()
@@ -26,12 +59,42 @@ func LoadConfig(path string) (v *viper.Viper, err error) {
 		cfg.RPCUser = v.GetString("rpc-user")
 		cfg.RPCPass = v.GetString("rpc-pass")
 		cfg.PlanetmintRPCHost = v.GetString("planetmint-rpc-host")
+		cfg.PlanetmintTLS = v.GetBool("planetmint-tls")
+		cfg.PlanetmintTLSCA = v.GetString("planetmint-tls-ca")
 		cfg.ServicePort = v.GetInt("service-port")
 		cfg.ServiceBind = v.GetString("service-bind")
 		cfg.AcceptedAsset = v.GetString("accepted-asset")
 		cfg.Wallet = v.GetString("wallet")
 		cfg.Confirmations = v.GetInt64("confirmations")
 		cfg.LogLevel = v.GetString("log-level")
+		cfg.LogFormat = v.GetString("log-format")
+		cfg.ArchiveRetention = v.GetDuration("archive-retention")
+		cfg.ConversionRate = v.GetUint64("conversion-rate")
+		cfg.RateSource = v.GetString("rate-source")
+		cfg.RateCacheTTL = v.GetDuration("rate-cache-ttl")
+		cfg.RateMaxAge = v.GetDuration("rate-max-age")
+		cfg.MinDeposit = v.GetFloat64("min-deposit")
+		cfg.MaxDeposit = v.GetFloat64("max-deposit")
+		cfg.AdminToken = v.GetString("admin-token")
+		cfg.AutoRefund = v.GetBool("auto-refund")
+		cfg.ConvertLateDeposits = v.GetBool("convert-late-deposits")
+		cfg.AddressTTL = v.GetDuration("address-ttl")
+		cfg.MinAddressTTL = v.GetDuration("min-address-ttl")
+		cfg.MaxAddressTTL = v.GetDuration("max-address-ttl")
+		cfg.CleanupInterval = v.GetDuration("cleanup-interval")
+		cfg.ConversionInterval = v.GetDuration("conversion-interval")
+		cfg.ConversionWorkers = v.GetInt("conversion-workers")
+		cfg.ReconciliationInterval = v.GetDuration("reconciliation-interval")
+		cfg.ReconciliationWindow = v.GetDuration("reconciliation-window")
+		cfg.RPCTimeout = v.GetDuration("rpc-timeout")
+		cfg.MintInclusionTimeout = v.GetDuration("mint-inclusion-timeout")
+		cfg.MintBatchSize = v.GetInt("mint-batch-size")
+		cfg.PlanetmintTxGas = v.GetUint64("planetmint-tx-gas")
+		cfg.DryRun = v.GetBool("dry-run")
+		cfg.SelfCheck = v.GetBool("self-check")
+		cfg.TraceExporter = v.GetString("trace-exporter")
+		cfg.OTLPEndpoint = v.GetString("otlp-endpoint")
+		cfg.OTLPTLS = v.GetBool("otlp-tls")
 		return
 	}
 	log.Println("no config file found.")
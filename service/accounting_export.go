@@ -0,0 +1,139 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+)
+
+// exportColumns is the header of the CSV export, it names the fields of types.ExportedDeposit like the
+// JSON export does.
+var exportColumns = []string{"conversion-id", "liquid-address", "planetmint-beneficiary", "liquid-tx-id", "state",
+	"confirmations", "rddl-amount", "conversion-rate", "remainder-credit", "plmnt-amount", "remainder",
+	"planetmint-tx-hash", "refund-tx-id", "created-at", "broadcast-at", "minted-at", "completed-at"}
+
+// ExportConversions passes the deposits of the active and archived conversions that were finished within
+// [from, to] to export one at a time, so that large ranges are not held in memory. The range applies to
+// the time every deposit reached its final state, deposits that are not finished are left out.
+// Conversions are visited in the order of their keys and it stops at the first error of export.
+func (r2p *R2PService) ExportConversions(ctx context.Context, from int64, to int64, export func(types.ExportedDeposit) error) (err error) {
+	iter := r2p.db.NewIterator(nil, nil)
+	defer iter.Release()
+
+	for ctx.Err() == nil && iter.Next() {
+		if !isConversionKey(iter.Key()) && !bytes.HasPrefix(iter.Key(), archivePrefix) {
+			continue
+		}
+		req, err := decodeConversionRequest(iter.Value())
+		if err != nil {
+			r2p.logger.Error("msg", "failed to unmarshal entry", "key", string(iter.Key()), "error", err)
+			continue
+		}
+		for _, deposit := range req.Deposits {
+			completedAt := depositCompletedAt(req, deposit)
+			if completedAt == 0 || completedAt < from || completedAt > to {
+				continue
+			}
+			if err = export(exportDeposit(req, deposit, completedAt)); err != nil {
+				return err
+			}
+		}
+	}
+	if err = iter.Error(); err != nil {
+		return
+	}
+	return ctx.Err()
+}
+
+// depositCompletedAt returns the time the deposit reached its final state, 0 if it is not finished. Deposits
+// finished before the time was recorded fall back to the time of their mint or of their conversion.
+func depositCompletedAt(req ConversionRequest, deposit types.Deposit) int64 {
+	if !isFinalDeposit(deposit.State) {
+		return 0
+	}
+	if deposit.CompletedAt != 0 {
+		return deposit.CompletedAt
+	}
+	if deposit.MintedAt != 0 {
+		return deposit.MintedAt
+	}
+	return req.completedAt()
+}
+
+func exportDeposit(req ConversionRequest, deposit types.Deposit, completedAt int64) types.ExportedDeposit {
+	return types.ExportedDeposit{
+		ConversionID:          req.ID,
+		LiquidAddress:         req.ConfidentialAddress,
+		PlanetmintBeneficiary: req.PlanetmintAddress,
+		LiquidTxID:            deposit.LiquidTxID,
+		State:                 deposit.State,
+		Confirmations:         deposit.Confirmations,
+		RDDLAmount:            deposit.RDDLAmount,
+		ConversionRate:        deposit.ConversionRate,
+		RemainderCredit:       deposit.RemainderCredit,
+		PLMNTAmount:           deposit.PLMNTAmount,
+		Remainder:             deposit.Remainder,
+		PlanetmintTxHash:      deposit.PlanetmintTxHash,
+		RefundTxID:            deposit.RefundTxID,
+		CreatedAt:             req.Timestamp,
+		BroadcastAt:           deposit.BroadcastAt,
+		MintedAt:              deposit.MintedAt,
+		CompletedAt:           completedAt,
+	}
+}
+
+// exportRecord returns the CSV record of the deposit in the order of exportColumns.
+func exportRecord(deposit types.ExportedDeposit) []string {
+	return []string{deposit.ConversionID, deposit.LiquidAddress, deposit.PlanetmintBeneficiary, deposit.LiquidTxID,
+		string(deposit.State), strconv.FormatUint(deposit.Confirmations, 10), strconv.FormatUint(deposit.RDDLAmount, 10),
+		strconv.FormatUint(deposit.ConversionRate, 10), strconv.FormatUint(deposit.RemainderCredit, 10),
+		strconv.FormatUint(deposit.PLMNTAmount, 10), strconv.FormatUint(deposit.Remainder, 10), deposit.PlanetmintTxHash,
+		deposit.RefundTxID, strconv.FormatInt(deposit.CreatedAt, 10), strconv.FormatInt(deposit.BroadcastAt, 10),
+		strconv.FormatInt(deposit.MintedAt, 10), strconv.FormatInt(deposit.CompletedAt, 10)}
+}
+
+// exportCSV streams the deposits to w as CSV with a header line.
+func (r2p *R2PService) exportCSV(ctx context.Context, from int64, to int64, w io.Writer) (err error) {
+	writer := csv.NewWriter(w)
+	if err = writer.Write(exportColumns); err != nil {
+		return
+	}
+	err = r2p.ExportConversions(ctx, from, to, func(deposit types.ExportedDeposit) error {
+		return writer.Write(exportRecord(deposit))
+	})
+	writer.Flush()
+	if err != nil {
+		return
+	}
+	return writer.Error()
+}
+
+// exportJSON streams the deposits to w as JSON array.
+func (r2p *R2PService) exportJSON(ctx context.Context, from int64, to int64, w io.Writer) (err error) {
+	if _, err = io.WriteString(w, "["); err != nil {
+		return
+	}
+	separator := ""
+	err = r2p.ExportConversions(ctx, from, to, func(deposit types.ExportedDeposit) error {
+		row, err := json.Marshal(deposit)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(w, separator); err != nil {
+			return err
+		}
+		separator = ","
+		_, err = w.Write(row)
+		return err
+	})
+	if err != nil {
+		return
+	}
+	_, err = io.WriteString(w, "]\n")
+	return
+}
@@ -0,0 +1,103 @@
+package service_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/service"
+	"github.com/rddl-network/rddl-2-plmnt-service/testutil"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportConversions(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.AdminToken = "secret"
+	defer func() { cfg.AdminToken = "" }()
+	r2p, router, _, _ := setupR2PService(t)
+
+	minted := service.ConversionRequest{
+		ID:                  "conversion-1",
+		ConfidentialAddress: testutil.ConfidentialAddr,
+		PlanetmintAddress:   testutil.PlanetmintAddress,
+		Timestamp:           1000,
+		State:               types.StateMintConfirmed,
+		History:             []types.StateTransition{{State: types.StateRegistered, Timestamp: 1000}, {State: types.StateMintConfirmed, Timestamp: 2000}},
+		Deposits: []types.Deposit{
+			{LiquidTxID: "tx-1", Confirmations: 10, RDDLAmount: 150000000, ConversionRate: 100, PLMNTAmount: 150, PlanetmintTxHash: "HASH1", BroadcastAt: 1900, MintedAt: 1950, State: types.StateMintConfirmed},
+			{LiquidTxID: "tx-2", Confirmations: 12, RDDLAmount: 50000000, ConversionRate: 100, PLMNTAmount: 50, PlanetmintTxHash: "HASH2", BroadcastAt: 1900, MintedAt: 1960, CompletedAt: 1960, State: types.StateMintConfirmed},
+			// minted after the exported range
+			{LiquidTxID: "tx-late", Confirmations: 10, RDDLAmount: 100000000, ConversionRate: 100, PLMNTAmount: 100, PlanetmintTxHash: "HASH3", BroadcastAt: 2900, MintedAt: 3000, CompletedAt: 3000, State: types.StateMintConfirmed},
+		},
+	}
+	// the dust deposit is not finished until it is refunded
+	dust := service.ConversionRequest{
+		ID:                  "conversion-2",
+		ConfidentialAddress: testutil.UnconfidentialAddr,
+		PlanetmintAddress:   testutil.PlanetmintAddress,
+		Timestamp:           5000,
+		State:               types.StateDust,
+		History:             []types.StateTransition{{State: types.StateDust, Timestamp: 6000}},
+		Deposits: []types.Deposit{
+			{LiquidTxID: "tx-3", RDDLAmount: 1000, State: types.StateDust},
+			{LiquidTxID: "tx-4", RDDLAmount: 2000, RefundTxID: "REFUND", CompletedAt: 5500, State: types.StateRefunded},
+		},
+	}
+	assert.NoError(t, r2p.StoreConversionRequest(minted))
+	assert.NoError(t, r2p.StoreConversionRequest(dust))
+
+	w := exportConversions(t, router, "?from=1500&to=2500")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	records, err := csv.NewReader(w.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"conversion-id", "liquid-address", "planetmint-beneficiary", "liquid-tx-id", "state", "confirmations", "rddl-amount", "conversion-rate",
+			"remainder-credit", "plmnt-amount", "remainder", "planetmint-tx-hash", "refund-tx-id", "created-at", "broadcast-at", "minted-at", "completed-at"},
+		{"conversion-1", testutil.ConfidentialAddr, testutil.PlanetmintAddress, "tx-1", "mint-confirmed", "10", "150000000", "100", "0", "150", "0", "HASH1", "", "1000", "1900", "1950", "1950"},
+		{"conversion-1", testutil.ConfidentialAddr, testutil.PlanetmintAddress, "tx-2", "mint-confirmed", "12", "50000000", "100", "0", "50", "0", "HASH2", "", "1000", "1900", "1960", "1960"},
+	}, records)
+
+	w = exportConversions(t, router, "?format=json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var deposits []types.ExportedDeposit
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &deposits))
+	assert.Len(t, deposits, 4)
+	byTx := make(map[string]types.ExportedDeposit)
+	for _, deposit := range deposits {
+		byTx[deposit.LiquidTxID] = deposit
+	}
+	assert.Equal(t, uint64(150), byTx["tx-1"].PLMNTAmount)
+	assert.Equal(t, int64(1950), byTx["tx-1"].CompletedAt)
+	assert.Equal(t, int64(3000), byTx["tx-late"].CompletedAt)
+	assert.Equal(t, types.StateRefunded, byTx["tx-4"].State)
+	assert.Equal(t, int64(5500), byTx["tx-4"].CompletedAt)
+	assert.Equal(t, "conversion-2", byTx["tx-4"].ConversionID)
+	assert.NotContains(t, byTx, "tx-3")
+
+	// an empty range is a valid export
+	w = exportConversions(t, router, "?format=json&from=3500&to=4000")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[]\n", w.Body.String())
+
+	w = exportConversions(t, router, "?format=xml")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = exportConversions(t, router, "?from=yesterday")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func exportConversions(t *testing.T, router http.Handler, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/admin/conversions/export"+query, nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	router.ServeHTTP(w, req)
+	return w
+}
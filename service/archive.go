@@ -0,0 +1,118 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// archivePrefix separates archived conversions from the conversions that are still being processed.
+var archivePrefix = []byte("archive/")
+
+func archiveKey(confidentialAddress string) []byte {
+	return append(bytes.Clone(archivePrefix), confidentialAddress...)
+}
+
+// isArchivable reports whether the conversion is finished and can be moved to the archive once its
+// monitoring window passed.
+func isArchivable(state types.ConversionState) bool {
+	return state == types.StateMintConfirmed || state == types.StateCredited || state == types.StateRefunded ||
+		state == types.StateExpired || state == types.StateSimulated
+}
+
+// completedAt returns the time the conversion entered its current state.
+func (req ConversionRequest) completedAt() int64 {
+	if len(req.History) == 0 {
+		return req.UpdatedAt
+	}
+	return req.History[len(req.History)-1].Timestamp
+}
+
+// archiveConversionRequest moves the conversion request from the active keyspace into the archive along
+// with the writes already in the batch.
+func (r2p *R2PService) archiveConversionRequest(convReq ConversionRequest, batch *leveldb.Batch) (err error) {
+	convReq.UpdatedAt = time.Now().Unix()
+	convReqBytes, err := json.Marshal(convReq)
+	if err != nil {
+		return
+	}
+
+	batch.Put(archiveKey(convReq.ConfidentialAddress), convReqBytes)
+	batch.Delete([]byte(convReq.ConfidentialAddress))
+
+	r2p.dbMutex.Lock()
+	err = r2p.db.Write(batch, nil)
+	r2p.dbMutex.Unlock()
+	return
+}
+
+// GetArchivedConversion returns the archived conversion request for the given Liquid receive address.
+func (r2p *R2PService) GetArchivedConversion(confidentialAddress string) (convReq ConversionRequest, err error) {
+	value, err := r2p.db.Get(archiveKey(confidentialAddress), nil)
+	if err != nil {
+		return
+	}
+	return decodeConversionRequest(value)
+}
+
+// GetArchivedConversions returns all archived conversion requests completed within [from, to].
+func (r2p *R2PService) GetArchivedConversions(from int64, to int64) (convReqs []ConversionRequest, err error) {
+	iter := r2p.db.NewIterator(util.BytesPrefix(archivePrefix), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		req, err := decodeConversionRequest(iter.Value())
+		if err != nil {
+			r2p.logger.Error("msg", "failed to unmarshal archived entry", "key", string(iter.Key()), "error", err)
+			continue
+		}
+		if req.completedAt() < from || req.completedAt() > to {
+			continue
+		}
+		convReqs = append(convReqs, req)
+	}
+	err = iter.Error()
+	return
+}
+
+// pruneArchive deletes archived conversions that are older than the configured retention period.
+func (r2p *R2PService) pruneArchive() {
+	cfg := config.GetConfig()
+	cutoff := time.Now().Add(-cfg.ArchiveRetention).Unix()
+
+	iter := r2p.db.NewIterator(util.BytesPrefix(archivePrefix), nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		req, err := decodeConversionRequest(iter.Value())
+		if err != nil {
+			r2p.logger.Error("msg", "failed to unmarshal archived entry", "key", string(iter.Key()), "error", err)
+			continue
+		}
+		if req.completedAt() < cutoff {
+			batch.Delete(bytes.Clone(iter.Key()))
+		}
+	}
+	if err := iter.Error(); err != nil {
+		r2p.logger.Error("msg", "iterating archive", "error", err)
+		return
+	}
+	if batch.Len() == 0 {
+		return
+	}
+
+	r2p.dbMutex.Lock()
+	err := r2p.db.Write(batch, nil)
+	r2p.dbMutex.Unlock()
+	if err != nil {
+		r2p.logger.Error("msg", "pruning archive", "error", err)
+		return
+	}
+	r2p.logger.Info("msg", "pruned archived conversions", "retention", cfg.ArchiveRetention.String(), "pruned", batch.Len())
+}
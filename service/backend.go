@@ -1,112 +1,315 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
-	"log"
+	"errors"
+	"slices"
+	"sync"
 	"time"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/syndtr/goleveldb/leveldb"
 )
 
 type ConversionRequest struct {
-	ConfidentialAddress string `binding:"required" json:"confidential-address"`
-	PlanetmintAddress   string `binding:"required" json:"planetmint-address"`
-	Timestamp           int64  `binding:"required" json:"timestamp"`
+	ID                  string                  `json:"id"`
+	ConfidentialAddress string                  `binding:"required" json:"confidential-address"`
+	PlanetmintAddress   string                  `binding:"required" json:"planetmint-address"`
+	RefundAddress       string                  `json:"refund-address"`
+	Timestamp           int64                   `binding:"required" json:"timestamp"`
+	ExpiresAt           int64                   `json:"expires-at"`
+	State               types.ConversionState   `json:"state"`
+	History             []types.StateTransition `json:"history"`
+	Deposits            []types.Deposit         `json:"deposits"`
+	LastError           string                  `json:"last-error"`
+	UpdatedAt           int64                   `json:"updated-at"`
+}
+
+// isConversionKey reports whether the key belongs to a conversion request. Conversion requests are keyed
+// by their receive address, all other records use a prefix.
+func isConversionKey(key []byte) bool {
+	return !bytes.HasPrefix(key, archivePrefix) && !bytes.HasPrefix(key, remainderPrefix)
+}
+
+func (req ConversionRequest) toResponse() (res types.ConversionResponse) {
+	res.ID = req.ID
+	res.LiquidAddress = req.ConfidentialAddress
+	res.PlanetmintBeneficiary = req.PlanetmintAddress
+	res.RefundAddress = req.RefundAddress
+	res.Timestamp = req.Timestamp
+	res.ExpiresAt = req.expiresAt()
+	res.State = req.State
+	res.History = req.History
+	res.Deposits = req.Deposits
+	res.LastError = req.LastError
+	res.UpdatedAt = req.UpdatedAt
+	return
 }
 
-func (r2p *R2PService) addConversionRequest(confidentialAddress string, planetmintAddress string) (err error) {
+// expiresAt returns the end of the monitoring window. Entries stored before the window was configurable
+// expire after the configured address-ttl.
+func (req ConversionRequest) expiresAt() int64 {
+	if req.ExpiresAt != 0 {
+		return req.ExpiresAt
+	}
+	return req.Timestamp + int64(config.GetConfig().AddressTTL.Seconds())
+}
+
+func decodeConversionRequest(value []byte) (req ConversionRequest, err error) {
+	err = json.Unmarshal(value, &req)
+	if err != nil {
+		return
+	}
+	// entries stored before the lifecycle was tracked have no state
+	req.initState()
+	// entries stored before conversions had an ID get one derived from their receive address
+	if req.ID == "" {
+		sum := sha256.Sum256([]byte(req.ConfidentialAddress))
+		req.ID = hex.EncodeToString(sum[:8])
+	}
+	return
+}
+
+func (r2p *R2PService) addConversionRequest(ctx context.Context, confidentialAddress string, planetmintAddress string, refundAddress string, ttl time.Duration) (convReq ConversionRequest, err error) {
 	// store receive address - planetmint address pair
-	var convReq ConversionRequest
+	convReq.ID = newID()
 	convReq.ConfidentialAddress = confidentialAddress
 	convReq.PlanetmintAddress = planetmintAddress
+	convReq.RefundAddress = refundAddress
 	now := time.Now()
 	convReq.Timestamp = now.Unix()
+	convReq.ExpiresAt = now.Add(ttl).Unix()
+	convReq.initState()
 
+	err = r2p.putConversionRequest(convReq, new(leveldb.Batch))
+	if err != nil {
+		r2p.log(withConversion(ctx, convReq)).Error("msg", "storing conversion failed", "error", err)
+		return
+	}
+	return
+}
+
+func (r2p *R2PService) putConversionRequest(convReq ConversionRequest, batch *leveldb.Batch) (err error) {
+	convReq.UpdatedAt = time.Now().Unix()
 	convReqBytes, err := json.Marshal(convReq)
 	if err != nil {
-		r2p.logger.Error("error", "Error serializing ConversionRequest: "+err.Error())
+		r2p.log(withConversion(context.Background(), convReq)).Error("msg", "serializing conversion failed", "error", err)
 		return
 	}
 
+	batch.Put([]byte(convReq.ConfidentialAddress), convReqBytes)
 	r2p.dbMutex.Lock()
-	err = r2p.db.Put([]byte(confidentialAddress), convReqBytes, nil)
+	err = r2p.db.Write(batch, nil)
 	r2p.dbMutex.Unlock()
+	return
+}
+
+// GetConversionRequest returns the conversion request stored for the given Liquid receive address.
+func (r2p *R2PService) GetConversionRequest(confidentialAddress string) (convReq ConversionRequest, err error) {
+	value, err := r2p.db.Get([]byte(confidentialAddress), nil)
 	if err != nil {
-		r2p.logger.Error("error", "storing addresses in DB: "+err.Error())
 		return
 	}
-	return
+	return decodeConversionRequest(value)
 }
 
-func (r2p *R2PService) deleteEntry(key []byte) (err error) {
-	r2p.dbMutex.Lock()
-	err = r2p.db.Delete(key, nil)
-	r2p.dbMutex.Unlock()
+// storeConversionRequest persists the conversion request. The remainders of deposits that failed or got
+// refunded are reverted with the same write. Finished conversions stay active, as the receive address takes
+// top-ups until the monitoring window passed, the cleanup pass archives them afterwards.
+func (r2p *R2PService) storeConversionRequest(convReq *ConversionRequest) (err error) {
+	unlockLedger := r2p.ledgerLocks.lock(convReq.PlanetmintAddress)
+	defer unlockLedger()
+
+	deposits := slices.Clone(convReq.Deposits)
+	batch := new(leveldb.Batch)
+	err = r2p.revertRemainders(convReq, batch)
+	if err == nil {
+		err = r2p.putConversionRequest(*convReq, batch)
+	}
+	if err != nil {
+		// the remainders are reverted with the next attempt to store the conversion
+		convReq.Deposits = deposits
+	}
 	return
 }
 
-func (r2p *R2PService) cleanupDB() {
+func (r2p *R2PService) cleanupDB(ctx context.Context) (err error) {
 	// Create an iterator for the database
 	iter := r2p.db.NewIterator(nil, nil)
 	defer iter.Release() // Make sure to release the iterator at the end
 
-	// Iterate over all elements in the database
-	for iter.Next() {
-		// Use iter.Key() and iter.Value() to access the key and value
+	// Iterate over all elements in the database, stop early on shutdown
+	for ctx.Err() == nil && iter.Next() {
+		// Use iter.Key() to access the key, the entry itself is re-read under its lock
 		key := iter.Key()
-		value := iter.Value()
-		var req ConversionRequest
-		err := json.Unmarshal(value, &req)
-		if err != nil {
-			log.Printf("Failed to unmarshal entry: %s - %v", string(key), err)
+		if !isConversionKey(key) {
 			continue
 		}
-		now := time.Now()
-		if now.Unix()-req.Timestamp > int64((12 * time.Hour).Seconds()) {
-			// If the entry is older than 12 hours, delete it
-			err := r2p.deleteEntry(key)
-			if err != nil {
-				log.Printf("Failed to delete entry: %v", err)
-			}
-		}
+		r2p.cleanupEntry(ctx, string(key))
 	}
 
 	// Check for any errors encountered during iteration
-	if err := iter.Error(); err != nil {
-		r2p.logger.Error("error", err.Error())
+	if err = iter.Error(); err != nil {
+		r2p.log(ctx).Error("msg", "iterating conversions failed", "error", err)
+		return
+	}
+
+	if err = ctx.Err(); err != nil {
+		return
 	}
+	r2p.pruneArchive()
+	return
 }
 
-func (r2p *R2PService) convertArrivedFunds() {
+// convertArrivedFunds processes the conversion requests in parallel with the configured number of workers.
+// Every entry is locked while it is processed and mints are locked per Liquid transaction, so a deposit is
+// only minted once. With a mint-batch-size above 1 the mints are collected and broadcast in batches once
+// all entries are processed. err is set if the pass did not get through all entries.
+func (r2p *R2PService) convertArrivedFunds(ctx context.Context) (err error) {
+	var batch *mintBatch
+	if config.GetConfig().MintBatchSize > 1 {
+		batch = newMintBatch()
+	}
+
+	keys := make(chan string)
+	var wg sync.WaitGroup
+	for range config.GetConfig().ConversionWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keys {
+				r2p.convertEntry(ctx, key, batch)
+			}
+		}()
+	}
+
 	// Create an iterator for the database, nil means the whole database
 	iter := r2p.db.NewIterator(nil, nil)
 	defer iter.Release()
 
-	// Start from the last key
-	for iter.Last(); iter.Valid(); iter.Prev() {
+	// Start from the last key, stop early on shutdown
+	var open int
+	for iter.Last(); ctx.Err() == nil && iter.Valid(); iter.Prev() {
 		key := iter.Key()
-		value := iter.Value()
-		msg := fmt.Sprintf("Key: %s, Value: %s\n", key, value)
-		r2p.logger.Debug("msg", msg)
-		var req ConversionRequest
-		err := json.Unmarshal(value, &req)
-		if err != nil {
-			r2p.logger.Error("error", fmt.Sprintf("Failed to unmarshal entry: %s - %v", string(key), err))
+		if !isConversionKey(key) {
 			continue
 		}
-		deleteEntry, err := r2p.ExecutePotentialConversion(req)
+		open++
+		r2p.log(ctx).Debug("msg", "queueing conversion", "liquid-address", string(key), "value", string(iter.Value()))
+		select {
+		case keys <- string(key):
+		case <-ctx.Done():
+		}
+	}
+	close(keys)
+	wg.Wait()
+
+	// Check for any errors found during iteration
+	if err = iter.Error(); err != nil {
+		r2p.log(ctx).Error("msg", "iterating conversions failed", "error", err)
+	}
+
+	if batch != nil {
+		r2p.broadcastMints(ctx, batch)
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		openConversions.Set(float64(open))
+	}
+	return
+}
+
+// cleanupEntry expires the conversion request once its monitoring window passed and archives it once it
+// is finished and its monitoring window passed. The entry is re-read under its lock as it may have changed
+// since the pass started.
+func (r2p *R2PService) cleanupEntry(ctx context.Context, key string) {
+	unlock := r2p.entryLocks.lock(key)
+	defer unlock()
+
+	req, err := r2p.GetConversionRequest(key)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		// archived in the meantime
+		return
+	}
+	if err != nil {
+		r2p.log(ctx).Error("msg", "reading conversion failed", "liquid-address", key, "error", err)
+		return
+	}
+	ctx = withConversion(ctx, req)
+	now := time.Now()
+	if req.State == types.StateRegistered && now.Unix() > req.expiresAt() {
+		// If no funds arrived within the monitoring window, stop monitoring the entry
+		err = r2p.expireConversionRequest(ctx, &req)
 		if err != nil {
-			r2p.logger.Error("error", fmt.Sprintf("Failed to convert entry: %s - %v", string(key), err))
+			r2p.log(ctx).Error("msg", "expiring conversion failed", "error", err)
+			return
 		}
-		if deleteEntry {
-			r2p.logger.Info("msg", fmt.Sprintf("delete entry: %s ", string(key)))
-			err = r2p.deleteEntry(key)
-			if err != nil {
-				r2p.logger.Error("error", fmt.Sprintf("deletion of entry %s failed: %s", string(key), err.Error()))
-			}
+	}
+	if isArchivable(req.State) && now.Unix() > req.expiresAt() {
+		// finished entries take top-ups until their monitoring window passed
+		err = r2p.archiveConversionRequest(req, new(leveldb.Batch))
+		if err != nil {
+			r2p.log(ctx).Error("msg", "archiving conversion failed", "error", err)
 		}
 	}
-	// Check for any errors found during iteration
-	if err := iter.Error(); err != nil {
-		log.Println(err.Error())
+}
+
+// convertEntry refunds or advances the conversion request. Finished conversion requests are checked for
+// top-ups until their monitoring window passed. Conversion requests past their monitoring window are
+// expired, as the cleanup pass may not have caught them yet, and are only advanced further if they
+// received funds within the window. The entry is re-read under its lock as it may have changed since the
+// pass started.
+func (r2p *R2PService) convertEntry(ctx context.Context, key string, batch *mintBatch) {
+	unlock := r2p.entryLocks.lock(key)
+	defer unlock()
+
+	req, err := r2p.GetConversionRequest(key)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		// archived in the meantime
+		return
+	}
+	if err != nil {
+		r2p.log(ctx).Error("msg", "reading conversion failed", "liquid-address", key, "error", err)
+		return
+	}
+	// executeConversion attaches the conversion to the log lines itself
+	logCtx := withConversion(ctx, req)
+	now := time.Now().Unix()
+	if req.State == types.StateRegistered && now > req.expiresAt() {
+		err = r2p.expireConversionRequest(logCtx, &req)
+		if err == nil {
+			err = r2p.storeConversionRequest(&req)
+		}
+		if err != nil {
+			r2p.log(logCtx).Error("msg", "expiring conversion failed", "error", err)
+			return
+		}
+		// deposits that arrived within the monitoring window are converted right away
+		if !isProcessable(req.State) {
+			return
+		}
+	}
+	// late deposits are converted or refunded by an operator unless convert-late-deposits is set
+	if config.GetConfig().AutoRefund && req.RefundAddress != "" && req.isRefundable() && req.State != types.StateLateDeposit {
+		err = r2p.RefundConversion(logCtx, &req)
+		if err != nil {
+			r2p.log(logCtx).Error("msg", "refunding conversion failed", "error", err)
+		}
+		return
+	}
+	topUp := isArchivable(req.State) && now <= req.expiresAt()
+	if !isProcessable(req.State) && !topUp && !(req.State == types.StateLateDeposit && config.GetConfig().ConvertLateDeposits) {
+		return
+	}
+	err = r2p.executeConversion(ctx, req, batch)
+	if err != nil {
+		r2p.log(logCtx).Error("msg", "processing conversion failed", "error", err)
 	}
 }
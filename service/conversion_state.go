@@ -0,0 +1,314 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+)
+
+// maxMintAttempts is the number of failed MintPLMNT calls after which a deposit is marked as failed.
+const maxMintAttempts = 3
+
+// conversionTransitions lists the states a conversion request may move to from a given state.
+// The state of a conversion is derived from its deposits, so it moves back to an earlier state
+// if a further deposit arrives, finished conversions do so until their monitoring window passed.
+// Terminal states have no outgoing transitions.
+var conversionTransitions = map[types.ConversionState][]types.ConversionState{
+	types.StateRegistered: {
+		types.StateFundsDetected,
+		types.StateConfirmed,
+		types.StateExpired,
+		types.StateNeedsReview,
+		types.StateLateDeposit,
+	},
+	// funds arrived after the monitoring window and got converted automatically or by an operator, or got refunded
+	types.StateLateDeposit: {
+		types.StateFundsDetected,
+		types.StateConfirmed,
+		types.StateNeedsReview,
+		types.StateRefunded,
+	},
+	types.StateFundsDetected: {
+		types.StateConfirmed,
+		types.StateNeedsReview,
+	},
+	types.StateConfirmed: {
+		types.StateFundsDetected,
+		types.StateMintBroadcast,
+		types.StateMintConfirmed,
+		types.StateCredited,
+		types.StateDust,
+		types.StateFailed,
+		types.StateNeedsReview,
+		types.StateSimulated,
+	},
+	types.StateMintBroadcast: {
+		types.StateFundsDetected,
+		types.StateConfirmed,
+		types.StateMintConfirmed,
+		types.StateDust,
+		types.StateFailed,
+		types.StateNeedsReview,
+	},
+	// an operator approved or refunded the deposits under review
+	types.StateNeedsReview: {
+		types.StateFundsDetected,
+		types.StateConfirmed,
+		types.StateMintBroadcast,
+		types.StateMintConfirmed,
+		types.StateRefunded,
+	},
+	types.StateFailed: {
+		types.StateMintConfirmed,
+		types.StateRefunded,
+	},
+	types.StateDust: {
+		types.StateMintConfirmed,
+		types.StateRefunded,
+	},
+	// a top-up arrived at the receive address of a finished conversion
+	types.StateMintConfirmed: topUpTransitions,
+	types.StateCredited:      topUpTransitions,
+	types.StateRefunded:      topUpTransitions,
+	types.StateSimulated:     topUpTransitions,
+	types.StateExpired:       {},
+}
+
+// topUpTransitions are the states a finished conversion moves to with a further deposit.
+var topUpTransitions = []types.ConversionState{
+	types.StateFundsDetected,
+	types.StateConfirmed,
+	types.StateNeedsReview,
+}
+
+// depositTransitions lists the states a single deposit may move to from a given state.
+var depositTransitions = map[types.ConversionState][]types.ConversionState{
+	// late deposits are refunded without being converted
+	types.StateFundsDetected: {
+		types.StateConfirmed,
+		types.StateNeedsReview,
+		types.StateRefunded,
+	},
+	types.StateConfirmed: {
+		types.StateMintBroadcast,
+		types.StateMintConfirmed,
+		types.StateCredited,
+		types.StateDust,
+		types.StateNeedsReview,
+		types.StateFailed,
+		types.StateRefunded,
+		types.StateSimulated,
+	},
+	// a mint that did not make it into a block is retried
+	types.StateMintBroadcast: {
+		types.StateConfirmed,
+		types.StateMintConfirmed,
+		types.StateFailed,
+	},
+	// approved deposits wait for their confirmations if they were put under review before
+	types.StateNeedsReview: {
+		types.StateFundsDetected,
+		types.StateConfirmed,
+		types.StateMintConfirmed,
+		types.StateRefunded,
+	},
+	types.StateFailed: {
+		types.StateMintConfirmed,
+		types.StateRefunded,
+	},
+	types.StateDust: {
+		types.StateMintConfirmed,
+		types.StateRefunded,
+	},
+	types.StateMintConfirmed: {},
+	types.StateCredited:      {},
+	types.StateRefunded:      {},
+	types.StateSimulated:     {},
+}
+
+// isFinalDeposit reports whether a deposit in the state is finished.
+func isFinalDeposit(state types.ConversionState) bool {
+	transitions, known := depositTransitions[state]
+	return known && len(transitions) == 0
+}
+
+// depositProgress orders the non-terminal deposit states, the least advanced deposit determines the
+// state of the conversion.
+var depositProgress = []types.ConversionState{
+	types.StateFundsDetected,
+	types.StateConfirmed,
+	types.StateMintBroadcast,
+}
+
+// IsTerminal reports whether no further transitions are possible from the state.
+func IsTerminal(state types.ConversionState) bool {
+	return len(conversionTransitions[state]) == 0
+}
+
+// processableStates are the states the conversion passes work on. Conversions in any other state are
+// either done or wait for an operator to approve or refund them.
+var processableStates = []types.ConversionState{
+	types.StateRegistered,
+	types.StateFundsDetected,
+	types.StateConfirmed,
+	types.StateMintBroadcast,
+}
+
+func isProcessable(state types.ConversionState) bool {
+	return slices.Contains(processableStates, state)
+}
+
+// refundableStates are the deposit states in which the deposit can be sent back to the requester.
+var refundableStates = []types.ConversionState{
+	types.StateDust,
+	types.StateFailed,
+	types.StateNeedsReview,
+}
+
+func validateTransition(transitions map[types.ConversionState][]types.ConversionState, from types.ConversionState, to types.ConversionState) (err error) {
+	if !slices.Contains(transitions[from], to) {
+		err = fmt.Errorf("invalid state transition from %s to %s", from, to)
+	}
+	return
+}
+
+// initState puts a conversion request without a state into the registered state.
+func (req *ConversionRequest) initState() {
+	if req.State != "" {
+		return
+	}
+	req.State = types.StateRegistered
+	req.History = []types.StateTransition{{State: types.StateRegistered, Timestamp: req.Timestamp}}
+}
+
+// Transition moves the conversion request to the given state and records the time of the transition.
+// Transitioning to the current state is a no-op.
+func (req *ConversionRequest) Transition(to types.ConversionState) (err error) {
+	if req.State == to {
+		return
+	}
+	err = validateTransition(conversionTransitions, req.State, to)
+	if err != nil {
+		err = fmt.Errorf("conversion %s: %w", req.ConfidentialAddress, err)
+		return
+	}
+	req.State = to
+	req.History = append(req.History, types.StateTransition{State: to, Timestamp: time.Now().Unix()})
+	return
+}
+
+// deposit returns the deposit with the given Liquid transaction id or nil if it is unknown.
+func (req *ConversionRequest) deposit(liquidTxID string) *types.Deposit {
+	for i := range req.Deposits {
+		if req.Deposits[i].LiquidTxID == liquidTxID {
+			return &req.Deposits[i]
+		}
+	}
+	return nil
+}
+
+// depositOutcome orders the final deposit states by precedence: once all deposits are finished the
+// conversion takes the state of the deposit that comes first.
+var depositOutcome = []types.ConversionState{
+	types.StateFailed,
+	types.StateDust,
+	types.StateMintConfirmed,
+	types.StateSimulated,
+	types.StateRefunded,
+	types.StateCredited,
+}
+
+// updateState derives the state of the conversion from its deposits. A deposit under review puts the
+// whole conversion under review, otherwise the least advanced pending deposit determines the state.
+// Once all deposits are finished the conversion is failed if any deposit failed, dust if any deposit
+// was dust, mint-confirmed if any deposit got minted, simulated if any deposit's mint was simulated,
+// refunded if any deposit got refunded and credited if all deposits were too small to be minted.
+func (req *ConversionRequest) updateState() (err error) {
+	if len(req.Deposits) == 0 {
+		return
+	}
+	progress := len(depositProgress)
+	outcome := len(depositOutcome)
+	for _, deposit := range req.Deposits {
+		if deposit.State == types.StateNeedsReview {
+			return req.Transition(types.StateNeedsReview)
+		}
+		if i := slices.Index(depositProgress, deposit.State); i >= 0 && i < progress {
+			progress = i
+		}
+		if i := slices.Index(depositOutcome, deposit.State); i >= 0 && i < outcome {
+			outcome = i
+		}
+	}
+	if progress < len(depositProgress) {
+		return req.Transition(depositProgress[progress])
+	}
+	return req.Transition(depositOutcome[outcome])
+}
+
+// Approve releases the deposits under review for minting. Deposits without enough confirmations go back to
+// funds-detected.
+func (req *ConversionRequest) Approve() (err error) {
+	approved := false
+	for i := range req.Deposits {
+		if req.Deposits[i].State != types.StateNeedsReview {
+			continue
+		}
+		to := types.StateConfirmed
+		if req.Deposits[i].Confirmations < uint64(config.GetConfig().Confirmations) {
+			to = types.StateFundsDetected
+		}
+		err = transitionDeposit(&req.Deposits[i], to)
+		if err != nil {
+			return
+		}
+		req.Deposits[i].Approved = true
+		approved = true
+	}
+	if !approved {
+		return fmt.Errorf("conversion %s has no deposits to approve", req.ConfidentialAddress)
+	}
+	return req.updateState()
+}
+
+// reviewPendingDeposits puts the deposits that are neither minted nor approved by an operator under review
+// and returns their number.
+func (req *ConversionRequest) reviewPendingDeposits() (reviewed int, err error) {
+	for i := range req.Deposits {
+		deposit := &req.Deposits[i]
+		if deposit.Approved || (deposit.State != types.StateFundsDetected && deposit.State != types.StateConfirmed) {
+			continue
+		}
+		err = transitionDeposit(deposit, types.StateNeedsReview)
+		if err != nil {
+			return
+		}
+		reviewed++
+	}
+	return
+}
+
+func transitionDeposit(deposit *types.Deposit, to types.ConversionState) (err error) {
+	if deposit.State == to {
+		return
+	}
+	err = validateTransition(depositTransitions, deposit.State, to)
+	if err != nil {
+		err = fmt.Errorf("deposit %s: %w", deposit.LiquidTxID, err)
+		return
+	}
+	deposit.State = to
+	// final states are entered once, the accounting export books the deposit by that time
+	if isFinalDeposit(to) {
+		deposit.CompletedAt = time.Now().Unix()
+	}
+	// mint-confirmed is final, so every minted deposit is counted once
+	if to == types.StateMintConfirmed {
+		deposit.MintedAt = deposit.CompletedAt
+		plmntMinted.WithLabelValues(config.GetConfig().AcceptedAsset).Add(float64(deposit.PLMNTAmount))
+	}
+	return
+}
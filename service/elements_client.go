@@ -1,25 +1,174 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
 	elementsrpc "github.com/rddl-network/elements-rpc"
 	"github.com/rddl-network/elements-rpc/types"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	r2ptypes "github.com/rddl-network/rddl-2-plmnt-service/types"
 )
 
 type IElementsClient interface {
-	GetNewAddress(url string, params []string) (address string, err error)
-	ListReceivedByAddress(url string, params []string) (receivedTx []types.ListReceivedByAddressResult, err error)
+	GetNewAddress(ctx context.Context, url string, params []string) (address string, err error)
+	ListReceivedByAddress(ctx context.Context, url string, params []string) (receivedTx []types.ListReceivedByAddressResult, err error)
+	GetTransaction(ctx context.Context, url string, params []string) (transaction types.GetTransactionResult, err error)
+	ListTransactions(ctx context.Context, url string, params []string) (transactions []r2ptypes.WalletTransaction, err error)
+	SendToAddress(ctx context.Context, url string, params []string) (txID string, err error)
+	ListWallets(ctx context.Context, url string, params []string) (wallets []string, err error)
+	ListWalletDir(ctx context.Context, url string, params []string) (wallets []string, err error)
+	DumpAssetLabels(ctx context.Context, url string, params []string) (labels map[string]string, err error)
+	GetBalance(ctx context.Context, url string, params []string) (balance types.GetBalanceResult, err error)
 }
 
-type ElementsClient struct{}
+// methods of the Elements RPC that elementsrpc has no constant for
+const (
+	methodListWalletDir    = "listwalletdir"
+	methodDumpAssetLabels  = "dumpassetlabels"
+	methodListTransactions = "listtransactions"
+)
+
+// categoryReceive is the category of the transaction details that pay an address of the wallet.
+const categoryReceive = "receive"
 
+// ElementsClient calls the Elements RPC like elementsrpc, but cancels the calls with their context.
+type ElementsClient struct {
+	client *http.Client
+}
+
+// NewElementsClient returns a client whose RPC calls time out after the configured rpc-timeout.
 func NewElementsClient() *ElementsClient {
-	return &ElementsClient{}
+	return &ElementsClient{client: &http.Client{Timeout: config.GetConfig().RPCTimeout}}
+}
+
+func (ec *ElementsClient) GetNewAddress(ctx context.Context, url string, params []string) (address string, err error) {
+	result, err := ec.sendRequest(ctx, url, types.MethodGetNewAddress, params)
+	if err != nil {
+		return
+	}
+	address = strings.ReplaceAll(string(result), "\"", "")
+	return
+}
+
+func (ec *ElementsClient) ListReceivedByAddress(ctx context.Context, url string, params []string) (receivedTx []types.ListReceivedByAddressResult, err error) {
+	result, err := ec.sendRequest(ctx, url, types.MethodListReceivedByAddress, params)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(result, &receivedTx)
+	return
+}
+
+func (ec *ElementsClient) GetTransaction(ctx context.Context, url string, params []string) (transaction types.GetTransactionResult, err error) {
+	result, err := ec.sendRequest(ctx, url, types.MethodGetTransaction, params)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(result, &transaction)
+	return
+}
+
+// ListTransactions returns the outputs of the most recent wallet transactions, oldest first.
+func (ec *ElementsClient) ListTransactions(ctx context.Context, url string, params []string) (transactions []r2ptypes.WalletTransaction, err error) {
+	result, err := ec.sendRequest(ctx, url, methodListTransactions, params)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(result, &transactions)
+	return
+}
+
+func (ec *ElementsClient) SendToAddress(ctx context.Context, url string, params []string) (txID string, err error) {
+	result, err := ec.sendRequest(ctx, url, types.MethodSendToAddress, params)
+	if err != nil {
+		return
+	}
+	txID = strings.ReplaceAll(string(result), "\"", "")
+	return
+}
+
+// ListWallets returns the names of the loaded wallets.
+func (ec *ElementsClient) ListWallets(ctx context.Context, url string, params []string) (wallets []string, err error) {
+	result, err := ec.sendRequest(ctx, url, types.MethodListWallets, params)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(result, &wallets)
+	return
+}
+
+// ListWalletDir returns the names of the wallets in the wallet directory, loaded or not.
+func (ec *ElementsClient) ListWalletDir(ctx context.Context, url string, params []string) (wallets []string, err error) {
+	result, err := ec.sendRequest(ctx, url, methodListWalletDir, params)
+	if err != nil {
+		return
+	}
+	var walletDir struct {
+		Wallets []struct {
+			Name string `json:"name"`
+		} `json:"wallets"`
+	}
+	err = json.Unmarshal(result, &walletDir)
+	for _, wallet := range walletDir.Wallets {
+		wallets = append(wallets, wallet.Name)
+	}
+	return
+}
+
+// DumpAssetLabels returns the asset ids of the assets labeled on the node by their label.
+func (ec *ElementsClient) DumpAssetLabels(ctx context.Context, url string, params []string) (labels map[string]string, err error) {
+	result, err := ec.sendRequest(ctx, url, methodDumpAssetLabels, params)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(result, &labels)
+	return
 }
 
-func (ec *ElementsClient) GetNewAddress(url string, params []string) (address string, err error) {
-	return elementsrpc.GetNewAddress(url, params)
+func (ec *ElementsClient) GetBalance(ctx context.Context, url string, params []string) (balance types.GetBalanceResult, err error) {
+	result, err := ec.sendRequest(ctx, url, types.MethodGetBalance, params)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(result, &balance)
+	return
 }
 
-func (ec *ElementsClient) ListReceivedByAddress(url string, params []string) (receivedTx []types.ListReceivedByAddressResult, err error) {
-	return elementsrpc.ListReceivedByAddress(url, params)
+// sendRequest is elementsrpc.SendRequest with a context.
+func (ec *ElementsClient) sendRequest(ctx context.Context, url string, method string, params []string) (result []byte, err error) {
+	defer func(started time.Time) { observeRPC("elements", method, started, err) }(time.Now())
+	jsonStr := fmt.Sprintf(`{"jsonrpc":"1.0","method":"%s","params":[%s]}`, method, elementsrpc.Parse(params))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(jsonStr))
+	if err != nil {
+		return
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := ec.client.Do(request)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+
+	var response types.Response
+	if err = json.Unmarshal(body, &response); err != nil {
+		return
+	}
+	if response.Error.Code != 0 {
+		err = fmt.Errorf("%s: %d", response.Error.Message, response.Error.Code)
+		return
+	}
+	return json.Marshal(response.Result)
 }
@@ -0,0 +1,87 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/service"
+	"github.com/rddl-network/rddl-2-plmnt-service/testutil"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElementsClient(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"jsonrpc":"1.0","method":"getnewaddress","params":[""]}`, string(body))
+		_, err = w.Write([]byte(`{"result":"` + testutil.ConfidentialAddr + `","error":{"code":0,"message":""}}`))
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	eClient := service.NewElementsClient()
+	address, err := eClient.GetNewAddress(context.Background(), mockServer.URL, []string{`""`})
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.ConfidentialAddr, address)
+}
+
+func TestElementsClientCancel(t *testing.T) {
+	release := make(chan struct{})
+	mockServer := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		<-release
+	}))
+	defer mockServer.Close()
+	defer close(release)
+
+	eClient := service.NewElementsClient()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := eClient.ListReceivedByAddress(ctx, mockServer.URL, []string{"0"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestElementsClientRPCError(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, err := w.Write([]byte(`{"result":null,"error":{"code":-5,"message":"Invalid or non-wallet transaction id"}}`))
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	eClient := service.NewElementsClient()
+	_, err := eClient.GetTransaction(context.Background(), mockServer.URL, []string{`"txid"`})
+	assert.EqualError(t, err, "Invalid or non-wallet transaction id: -5")
+}
+
+func TestElementsClientListWalletDir(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, err := w.Write([]byte(`{"result":{"wallets":[{"name":""},{"name":"rddl2plmnt"}]},"error":null}`))
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	eClient := service.NewElementsClient()
+	wallets, err := eClient.ListWalletDir(context.Background(), mockServer.URL, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"", "rddl2plmnt"}, wallets)
+}
+
+func TestElementsClientListTransactions(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"jsonrpc":"1.0","method":"listtransactions","params":["*",1000,0,true]}`, string(body))
+		_, err = w.Write([]byte(`{"result":[{"address":"addr","category":"receive","amount":1.5,"asset":"asset","txid":"txid","vout":1,"time":1700000000}],"error":null}`))
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	eClient := service.NewElementsClient()
+	txs, err := eClient.ListTransactions(context.Background(), mockServer.URL, []string{`"*"`, "1000", "0", "true"})
+	assert.NoError(t, err)
+	assert.Equal(t, []types.WalletTransaction{{Address: "addr", Category: "receive", Amount: 1.5, Asset: "asset", TxID: "txid", Vout: 1, Time: 1700000000}}, txs)
+}
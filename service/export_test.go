@@ -0,0 +1,27 @@
+package service
+
+import "context"
+
+// CleanupDB and ConvertArrivedFunds expose the periodic tasks to the tests.
+func (r2p *R2PService) CleanupDB() {
+	_ = r2p.cleanupDB(context.Background())
+}
+
+func (r2p *R2PService) ConvertArrivedFunds() {
+	_ = r2p.convertArrivedFunds(context.Background())
+}
+
+// StartConversionPass triggers a conversion pass like a tick of the conversion ticker.
+func (r2p *R2PService) StartConversionPass() {
+	r2p.startPass(r2p.conversionPass)
+}
+
+// StoreConversionRequest persists the conversion request like the conversion pass does.
+func (r2p *R2PService) StoreConversionRequest(convReq ConversionRequest) error {
+	return r2p.storeConversionRequest(&convReq)
+}
+
+// StartReconciliationPass triggers a reconciliation pass like a tick of the reconciliation ticker.
+func (r2p *R2PService) StartReconciliationPass() {
+	r2p.startPass(r2p.reconciliationPass)
+}
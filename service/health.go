@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+)
+
+// readinessTimeout bounds the dependency checks of a readiness probe.
+const readinessTimeout = 5 * time.Second
+
+// getLiveness reports that the process is alive and serving HTTP.
+func (r2p *R2PService) getLiveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// getReadiness reports the reachability of Elements, Planetmint and the database together with the
+// time since the last complete conversion pass. It responds with 503 if any dependency is down.
+func (r2p *R2PService) getReadiness(c *gin.Context) {
+	resBody := r2p.GetReadiness(c.Request.Context())
+	if !resBody.Ready {
+		c.JSON(http.StatusServiceUnavailable, resBody)
+		return
+	}
+	c.JSON(http.StatusOK, resBody)
+}
+
+// GetReadiness checks the dependencies of the service in parallel.
+func (r2p *R2PService) GetReadiness(ctx context.Context) (readiness types.ReadinessResponse) {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	checks := map[string]func(ctx context.Context) error{
+		"elements":   r2p.checkElements,
+		"planetmint": r2p.pmClient.Health,
+		"database":   r2p.checkDatabase,
+	}
+	readiness.Ready = true
+	readiness.Dependencies = make(map[string]types.DependencyStatus, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := types.DependencyStatus{Up: true}
+			if err := check(ctx); err != nil {
+				status = types.DependencyStatus{Error: err.Error()}
+			}
+			mu.Lock()
+			readiness.Dependencies[name] = status
+			readiness.Ready = readiness.Ready && status.Up
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	readiness.LastConversionPass = r2p.conversionPass.getStatus().LastSuccess
+	if readiness.LastConversionPass != 0 {
+		readiness.SecondsSinceConversionPass = time.Now().Unix() - readiness.LastConversionPass
+	}
+	return
+}
+
+func (r2p *R2PService) checkElements(ctx context.Context) (err error) {
+	_, err = r2p.eClient.ListWallets(ctx, config.GetConfig().GetElementsURL(), []string{})
+	return
+}
+
+// checkDatabase fails once the database is closed.
+func (r2p *R2PService) checkDatabase(_ context.Context) (err error) {
+	_, err = r2p.db.GetProperty("leveldb.num-files-at-level0")
+	return
+}
@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveness(t *testing.T) {
+	_, router, _, _ := setupR2PService(t)
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/healthz", nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestReadiness(t *testing.T) {
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	eClientMock.EXPECT().ListWallets(gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{"rddl2plmnt"}, nil)
+	pmClientMock.EXPECT().Health(gomock.Any()).Return(nil)
+	readiness, code := getReadiness(t, router)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, readiness.Ready)
+	assert.Equal(t, map[string]types.DependencyStatus{
+		"elements":   {Up: true},
+		"planetmint": {Up: true},
+		"database":   {Up: true},
+	}, readiness.Dependencies)
+	assert.Equal(t, int64(0), readiness.LastConversionPass)
+
+	// a complete conversion pass is reported
+	r2p.StartConversionPass()
+	assert.Eventually(t, func() bool { return r2p.GetPassStatus()["conversion"].LastSuccess != 0 }, time.Second, 10*time.Millisecond)
+	eClientMock.EXPECT().ListWallets(gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{"rddl2plmnt"}, nil)
+	pmClientMock.EXPECT().Health(gomock.Any()).Return(nil)
+	readiness, code = getReadiness(t, router)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, r2p.GetPassStatus()["conversion"].LastSuccess, readiness.LastConversionPass)
+}
+
+func TestReadinessDependencyDown(t *testing.T) {
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	eClientMock.EXPECT().ListWallets(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("connection refused"))
+	pmClientMock.EXPECT().Health(gomock.Any()).Return(nil)
+	readiness, code := getReadiness(t, router)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, readiness.Ready)
+	assert.Equal(t, types.DependencyStatus{Error: "connection refused"}, readiness.Dependencies["elements"])
+	assert.True(t, readiness.Dependencies["planetmint"].Up)
+	assert.True(t, readiness.Dependencies["database"].Up)
+
+	// the database is down once it is closed
+	assert.NoError(t, r2p.Shutdown(context.Background()))
+	eClientMock.EXPECT().ListWallets(gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{"rddl2plmnt"}, nil)
+	pmClientMock.EXPECT().Health(gomock.Any()).Return(nil)
+	readiness, code = getReadiness(t, router)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, readiness.Dependencies["database"].Up)
+}
+
+func getReadiness(t *testing.T, router *gin.Engine) (readiness types.ReadinessResponse, code int) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/readyz", nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, req)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &readiness))
+	return readiness, w.Code
+}
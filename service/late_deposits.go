@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	elementstypes "github.com/rddl-network/elements-rpc/types"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// expireConversionRequest expires a conversion whose monitoring window passed without funds. A final
+// check of the receive address catches deposits that arrived after the last conversion pass. If one of
+// them arrived within the monitoring window, the conversion is processed like any other funded
+// conversion. Otherwise it is moved to the late-deposit queue instead of being expired. The deposits are
+// recorded either way, so that late deposits can be refunded instead of being converted.
+func (r2p *R2PService) expireConversionRequest(ctx context.Context, conversion *ConversionRequest) (err error) {
+	cfg := config.GetConfig()
+	txDetails, err := r2p.eClient.ListReceivedByAddress(ctx, cfg.GetElementsURL(),
+		[]string{"0", "false", "true", `"` + conversion.ConfidentialAddress + `"`, `"` + cfg.AcceptedAsset + `"`})
+	if err != nil {
+		r2p.log(ctx).Error("msg", "listing received transactions failed", "error", err)
+		return errors.New("error: invalid call to rpc with address " + conversion.ConfidentialAddress + " : " + err.Error())
+	}
+	if len(txDetails) == 0 {
+		return conversion.Transition(types.StateExpired)
+	}
+
+	late := true
+	for _, details := range txDetails {
+		for _, txID := range details.TxIDs {
+			if conversion.deposit(txID) != nil {
+				continue
+			}
+			tx, err := r2p.eClient.GetTransaction(ctx, cfg.GetElementsURL(), []string{`"` + txID + `"`})
+			if err != nil {
+				r2p.log(ctx).Error("msg", "fetching transaction failed", "txid", txID, "error", err)
+				return errors.New("error: fetching tx " + txID + " received by " + conversion.ConfidentialAddress + " : " + err.Error())
+			}
+			if arrived := receivedAt(tx); arrived != 0 && arrived <= conversion.expiresAt() {
+				late = false
+			}
+			// the deposits are checked again by the conversion, as they are still funds-detected
+			conversion.Deposits = append(conversion.Deposits, types.Deposit{
+				LiquidTxID:    txID,
+				Confirmations: uint64(max(tx.Confirmations, 0)),
+				RDDLAmount:    receivedAmount(tx, conversion.ConfidentialAddress),
+				State:         types.StateFundsDetected,
+			})
+			depositsDetected.Inc()
+		}
+	}
+	if late {
+		r2p.log(ctx).Info("msg", "funds received after the monitoring window")
+		err = conversion.Transition(types.StateLateDeposit)
+	} else {
+		r2p.log(ctx).Info("msg", "funds received within the monitoring window were not processed yet")
+		err = conversion.updateState()
+	}
+	if err != nil {
+		return
+	}
+	return r2p.putConversionRequest(*conversion, new(leveldb.Batch))
+}
+
+// receivedAt returns the time the wallet received the transaction, falling back to the time of its block.
+// It is 0 if the wallet does not know either.
+func receivedAt(tx elementstypes.GetTransactionResult) int64 {
+	if tx.TimeReceived != 0 {
+		return tx.TimeReceived
+	}
+	if tx.Time != 0 {
+		return tx.Time
+	}
+	return tx.BlockTime
+}
+
+// GetLateDeposits returns the conversions that received funds after their monitoring window and wait
+// to be converted.
+func (r2p *R2PService) GetLateDeposits() (convReqs []ConversionRequest, err error) {
+	iter := r2p.db.NewIterator(nil, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if !isConversionKey(iter.Key()) {
+			continue
+		}
+		req, err := decodeConversionRequest(iter.Value())
+		if err != nil {
+			r2p.logger.Error("msg", "failed to unmarshal entry", "key", string(iter.Key()), "error", err)
+			continue
+		}
+		if req.State == types.StateLateDeposit {
+			convReqs = append(convReqs, req)
+		}
+	}
+	err = iter.Error()
+	return
+}
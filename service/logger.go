@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+)
+
+// Logger writes leveled log lines of key-value pairs. The AppLogger of go-utils satisfies it.
+type Logger interface {
+	Error(keyvals ...interface{})
+	Warn(keyvals ...interface{})
+	Info(keyvals ...interface{})
+	Debug(keyvals ...interface{})
+}
+
+// callerDepth makes the caller field point to the code calling the Logger methods.
+const callerDepth = 6
+
+// StructuredLogger is a Logger writing logfmt or JSON lines that can carry fields added with With.
+type StructuredLogger struct {
+	logger kitlog.Logger
+}
+
+// NewLogger returns a logger writing lines of the given log-level and above to w in the given log-format.
+func NewLogger(w io.Writer, logLevel string, logFormat string) (logger StructuredLogger, err error) {
+	var base kitlog.Logger
+	if logFormat == config.LogFormatJSON {
+		base = kitlog.NewJSONLogger(kitlog.NewSyncWriter(w))
+	} else {
+		base = kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(w))
+	}
+	base = kitlog.With(base, "ts", kitlog.DefaultTimestampUTC, "caller", kitlog.Caller(callerDepth))
+
+	option, err := levelOption(logLevel)
+	if err != nil {
+		return
+	}
+	return StructuredLogger{logger: level.NewFilter(base, option)}, nil
+}
+
+func levelOption(logLevel string) (option level.Option, err error) {
+	if logLevel == "debug" {
+		return level.AllowDebug(), nil
+	}
+	if logLevel == "info" {
+		return level.AllowInfo(), nil
+	}
+	if logLevel == "warn" {
+		return level.AllowWarn(), nil
+	}
+	if logLevel == "error" {
+		return level.AllowError(), nil
+	}
+	return nil, fmt.Errorf("unknown log-level %s", logLevel)
+}
+
+// With returns a logger adding the key-value pairs to every line.
+func (sl StructuredLogger) With(keyvals ...interface{}) Logger {
+	return StructuredLogger{logger: kitlog.With(sl.logger, keyvals...)}
+}
+
+func (sl StructuredLogger) Error(keyvals ...interface{}) {
+	_ = level.Error(sl.logger).Log(keyvals...)
+}
+
+func (sl StructuredLogger) Warn(keyvals ...interface{}) {
+	_ = level.Warn(sl.logger).Log(keyvals...)
+}
+
+func (sl StructuredLogger) Info(keyvals ...interface{}) {
+	_ = level.Info(sl.logger).Log(keyvals...)
+}
+
+func (sl StructuredLogger) Debug(keyvals ...interface{}) {
+	_ = level.Debug(sl.logger).Log(keyvals...)
+}
+
+// fieldLogger adds key-value pairs to the lines of loggers that cannot carry fields themselves.
+type fieldLogger struct {
+	logger Logger
+	fields []interface{}
+}
+
+func (fl fieldLogger) Error(keyvals ...interface{}) {
+	fl.logger.Error(append(keyvals, fl.fields...)...)
+}
+
+func (fl fieldLogger) Warn(keyvals ...interface{}) {
+	fl.logger.Warn(append(keyvals, fl.fields...)...)
+}
+
+func (fl fieldLogger) Info(keyvals ...interface{}) {
+	fl.logger.Info(append(keyvals, fl.fields...)...)
+}
+
+func (fl fieldLogger) Debug(keyvals ...interface{}) {
+	fl.logger.Debug(append(keyvals, fl.fields...)...)
+}
+
+type logFieldsKey struct{}
+
+// withLogFields returns a context whose log lines carry the key-value pairs in addition to the fields
+// already attached to ctx.
+func withLogFields(ctx context.Context, keyvals ...interface{}) context.Context {
+	fields, _ := ctx.Value(logFieldsKey{}).([]interface{})
+	return context.WithValue(ctx, logFieldsKey{}, append(fields[:len(fields):len(fields)], keyvals...))
+}
+
+// withConversion attaches the identifying fields of the conversion to the log lines of ctx.
+func withConversion(ctx context.Context, conversion ConversionRequest) context.Context {
+	return withLogFields(ctx, "conversion-id", conversion.ID, "liquid-address", conversion.ConfidentialAddress,
+		"beneficiary", conversion.PlanetmintAddress)
+}
+
+// log returns the logger adding the fields attached to ctx.
+func (r2p *R2PService) log(ctx context.Context) Logger {
+	fields, _ := ctx.Value(logFieldsKey{}).([]interface{})
+	if len(fields) == 0 {
+		return r2p.logger
+	}
+	if sl, ok := r2p.logger.(StructuredLogger); ok {
+		return sl.With(fields...)
+	}
+	return fieldLogger{logger: r2p.logger, fields: fields}
+}
+
+// newID returns a random ID of 16 hex characters for conversions and requests.
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
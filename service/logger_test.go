@@ -0,0 +1,128 @@
+package service_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/service"
+	"github.com/rddl-network/rddl-2-plmnt-service/testutil"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := service.NewLogger(&buf, "info", config.LogFormatJSON)
+	assert.NoError(t, err)
+	logger.Debug("msg", "dropped")
+	logger.With("conversion-id", "42").Info("msg", "kept")
+
+	lines := logLines(t, &buf)
+	assert.Len(t, lines, 1)
+	assert.Equal(t, "kept", lines[0]["msg"])
+	assert.Equal(t, "info", lines[0]["level"])
+	assert.Equal(t, "42", lines[0]["conversion-id"])
+	assert.Contains(t, lines[0]["caller"], "logger_test.go:")
+	assert.NotEmpty(t, lines[0]["ts"])
+
+	buf.Reset()
+	logger, err = service.NewLogger(&buf, "debug", config.LogFormatLogfmt)
+	assert.NoError(t, err)
+	logger.Debug("msg", "kept", "txid", "abc")
+	assert.Contains(t, buf.String(), "level=debug")
+	assert.Contains(t, buf.String(), "msg=kept txid=abc")
+
+	_, err = service.NewLogger(&buf, "verbose", config.LogFormatLogfmt)
+	assert.Error(t, err)
+}
+
+func TestRequestAndConversionIDs(t *testing.T) {
+	var buf bytes.Buffer
+	r2p, router, eClientMock := setupLoggingR2PService(t, &buf)
+
+	eClientMock.EXPECT().GetNewAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ConfidentialAddr, nil)
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/receiveaddress/"+testutil.PlanetmintAddress, nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-Request-ID", "caller-request")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "caller-request", w.Header().Get("X-Request-ID"))
+
+	var res types.ReceiveAddressResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Len(t, res.ConversionID, 16)
+	conversion := storedConversion(t, r2p, testutil.ConfidentialAddr)
+	assert.Equal(t, res.ConversionID, conversion.ID)
+
+	issued := findLogLine(t, &buf, "receive address issued")
+	assert.Equal(t, "caller-request", issued["request-id"])
+	assert.Equal(t, res.ConversionID, issued["conversion-id"])
+	assert.Equal(t, testutil.ConfidentialAddr, issued["liquid-address"])
+	assert.Equal(t, testutil.PlanetmintAddress, issued["beneficiary"])
+	assert.Contains(t, issued["caller"], "router.go:")
+	handled := findLogLine(t, &buf, "request handled")
+	assert.Equal(t, "caller-request", handled["request-id"])
+	assert.Equal(t, float64(http.StatusOK), handled["status"])
+
+	// requests without an ID get one assigned
+	buf.Reset()
+	w = httptest.NewRecorder()
+	req, err = http.NewRequestWithContext(context.Background(), http.MethodGet, "/conversion/"+testutil.ConfidentialAddr, nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, w.Header().Get("X-Request-ID"), 16)
+	assert.Equal(t, w.Header().Get("X-Request-ID"), findLogLine(t, &buf, "request handled")["request-id"])
+
+	var status types.ConversionResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, res.ConversionID, status.ID)
+}
+
+func setupLoggingR2PService(t *testing.T, w *bytes.Buffer) (r2p *service.R2PService, router *gin.Engine, eClientMock *testutil.MockIElementsClient) {
+	t.Helper()
+	logger, err := service.NewLogger(w, "debug", config.LogFormatJSON)
+	assert.NoError(t, err)
+	router = gin.New()
+	ctrl := gomock.NewController(t)
+	eClientMock = testutil.NewMockIElementsClient(ctrl)
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	r2p = service.NewR2PService(router, testutil.NewMockIPlanetmintClient(ctrl), eClientMock, service.NewStaticRateProvider(100), db, logger)
+	return
+}
+
+func logLines(t *testing.T, buf *bytes.Buffer) (lines []map[string]interface{}) {
+	t.Helper()
+	scanner := bufio.NewScanner(strings.NewReader(buf.String()))
+	for scanner.Scan() {
+		var line map[string]interface{}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	return
+}
+
+func findLogLine(t *testing.T, buf *bytes.Buffer, msg string) (line map[string]interface{}) {
+	t.Helper()
+	for _, l := range logLines(t, buf) {
+		if l["msg"] == msg {
+			return l
+		}
+	}
+	t.Fatalf("no log line %q in %s", msg, buf.String())
+	return
+}
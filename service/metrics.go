@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+)
+
+// Metrics of the conversion pipeline, served on /metrics together with the Go runtime and process metrics.
+var (
+	addressesIssued = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "r2p_receive_addresses_issued_total",
+		Help: "Receive addresses issued to beneficiaries.",
+	})
+	openConversions = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "r2p_open_conversions",
+		Help: "Conversion requests in the database that are not archived, as counted by the last complete conversion pass.",
+	})
+	depositsDetected = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "r2p_deposits_detected_total",
+		Help: "Deposits detected on receive addresses.",
+	})
+	rddlReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "r2p_rddl_received_total",
+		Help: "RDDL received with confirmed deposits.",
+	}, []string{"asset"})
+	plmntMinted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "r2p_plmnt_minted_total",
+		Help: "PLMNT minted for confirmed deposits of the asset.",
+	}, []string{"asset"})
+	mintFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "r2p_mint_failures_total",
+		Help: "Failed mint attempts by reason.",
+	}, []string{"reason"})
+	rpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "r2p_rpc_duration_seconds",
+		Help:    "Latency of the calls to Elements and Planetmint.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "method"})
+	rpcErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "r2p_rpc_errors_total",
+		Help: "Failed calls to Elements and Planetmint.",
+	}, []string{"service", "method"})
+	passDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "r2p_pass_duration_seconds",
+		Help:    "Duration of the cleanup and conversion passes.",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+	}, []string{"pass"})
+	reconciliationIssues = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "r2p_reconciliation_issues",
+		Help: "Issues found by the last periodic reconciliation by kind.",
+	}, []string{"kind"})
+)
+
+// Reasons of failed mint attempts.
+const (
+	mintFailureError       = "error"        // the mint could not be broadcast or simulated
+	mintFailureRejected    = "rejected"     // the mint transaction was rejected on broadcast
+	mintFailureFailed      = "failed"       // the mint transaction failed in its block
+	mintFailureNotIncluded = "not-included" // the mint transaction did not make it into a block in time
+)
+
+// observeRPC records the latency and the outcome of a call to Elements or Planetmint.
+func observeRPC(service string, method string, started time.Time, err error) {
+	rpcDuration.WithLabelValues(service, method).Observe(time.Since(started).Seconds())
+	if err != nil {
+		rpcErrors.WithLabelValues(service, method).Inc()
+	}
+}
+
+// observePlanetmintRPC records the calls on the Planetmint gRPC connection.
+func observePlanetmintRPC(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (err error) {
+	started := time.Now()
+	err = invoker(ctx, method, req, reply, cc, opts...)
+	observeRPC("planetmint", method, started, err)
+	return
+}
@@ -0,0 +1,98 @@
+package service_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	daotypes "github.com/planetmint/planetmint-go/x/dao/types"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/service"
+	"github.com/rddl-network/rddl-2-plmnt-service/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	detected := `r2p_deposits_detected_total`
+	received := `r2p_rddl_received_total{asset="` + cfg.AcceptedAsset + `"}`
+	minted := `r2p_plmnt_minted_total{asset="` + cfg.AcceptedAsset + `"}`
+	issued := `r2p_receive_addresses_issued_total`
+	before := scrapeMetrics(t, router)
+
+	eClientMock.EXPECT().GetNewAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ConfidentialAddr, nil)
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/receiveaddress/"+testutil.PlanetmintAddress, nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// a deposit of 2 RDDL is detected, confirmed and minted
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil).Times(2)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of1Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), gomock.Any()).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.MintTxResult, nil)
+	assert.NoError(t, r2p.ExecutePotentialConversion(context.Background(), storedConversion(t, r2p, testutil.ConfidentialAddr)))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), gomock.Any()).Return(&daotypes.QueryGetMintRequestsByHashResponse{}, nil)
+	assert.NoError(t, r2p.ExecutePotentialConversion(context.Background(), storedConversion(t, r2p, testutil.ConfidentialAddr)))
+
+	after := scrapeMetrics(t, router)
+	assert.Equal(t, float64(1), after[issued]-before[issued])
+	assert.Equal(t, float64(1), after[detected]-before[detected])
+	assert.Equal(t, float64(2), after[received]-before[received])
+	assert.Equal(t, float64(200), after[minted]-before[minted])
+}
+
+func TestRPCMetrics(t *testing.T) {
+	_, router, _, _ := setupR2PService(t)
+	failures := `r2p_rpc_errors_total{method="getnewaddress",service="elements"}`
+	calls := `r2p_rpc_duration_seconds_count{method="getnewaddress",service="elements"}`
+	before := scrapeMetrics(t, router)
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, err := w.Write([]byte(`{"result":null,"error":{"code":-18,"message":"Requested wallet does not exist or is not loaded"}}`))
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+	_, err := service.NewElementsClient().GetNewAddress(context.Background(), mockServer.URL, []string{`""`})
+	assert.Error(t, err)
+
+	after := scrapeMetrics(t, router)
+	assert.Equal(t, float64(1), after[calls]-before[calls])
+	assert.Equal(t, float64(1), after[failures]-before[failures])
+}
+
+// scrapeMetrics returns the samples served on /metrics by metric name including the labels.
+func scrapeMetrics(t *testing.T, router *gin.Engine) (samples map[string]float64) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/metrics", nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	samples = make(map[string]float64)
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		if i < 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		assert.NoError(t, err)
+		samples[line[:i]] = value
+	}
+	return
+}
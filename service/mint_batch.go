@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	daotypes "github.com/planetmint/planetmint-go/x/dao/types"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// mintBatch collects the deposits that are ready to be minted during a conversion pass. They are broadcast
+// in transactions of up to mint-batch-size mint requests once all conversions of the pass are processed.
+type mintBatch struct {
+	mu     sync.Mutex
+	mints  []queuedMint
+	queued map[string]bool
+}
+
+// queuedMint is a deposit waiting for its mint to be broadcast.
+type queuedMint struct {
+	key         string
+	beneficiary string
+	liquidTxID  string
+	amount      uint64
+}
+
+func newMintBatch() *mintBatch {
+	return &mintBatch{queued: make(map[string]bool)}
+}
+
+// add queues the mint of the deposit. A Liquid transaction is only queued once, even if it paid several
+// receive addresses.
+func (b *mintBatch) add(key string, beneficiary string, deposit *types.Deposit) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.queued[deposit.LiquidTxID] {
+		return
+	}
+	b.queued[deposit.LiquidTxID] = true
+	b.mints = append(b.mints, queuedMint{
+		key:         key,
+		beneficiary: beneficiary,
+		liquidTxID:  deposit.LiquidTxID,
+		amount:      deposit.PLMNTAmount,
+	})
+}
+
+// queueMint prepares the mint of a confirmed deposit and queues it for the batched broadcast.
+func (r2p *R2PService) queueMint(ctx context.Context, conversion *ConversionRequest, deposit *types.Deposit, batch *mintBatch) (err error) {
+	unlock := r2p.txLocks.lock(deposit.LiquidTxID)
+	defer unlock()
+
+	ready, err := r2p.prepareMint(ctx, conversion, deposit)
+	if err != nil || !ready {
+		return
+	}
+	batch.add(conversion.ConfidentialAddress, conversion.PlanetmintAddress, deposit)
+	return
+}
+
+// broadcastMints broadcasts the queued mints in transactions of up to mint-batch-size mint requests.
+func (r2p *R2PService) broadcastMints(ctx context.Context, batch *mintBatch) {
+	size := config.GetConfig().MintBatchSize
+	for start := 0; start < len(batch.mints) && ctx.Err() == nil; start += size {
+		r2p.broadcastMintBatch(ctx, batch.mints[start:min(start+size, len(batch.mints))])
+	}
+}
+
+// broadcastMintBatch broadcasts the mints in a single transaction and records the outcome with every
+// conversion. If the transaction fails as a whole, the mints are broadcast individually so that a single
+// bad mint request does not hold back the others.
+func (r2p *R2PService) broadcastMintBatch(ctx context.Context, mints []queuedMint) {
+	unlock := r2p.lockMints(mints)
+	defer unlock()
+
+	conversions, ready, deposits := r2p.readyMints(ctx, mints)
+	if len(ready) == 0 {
+		return
+	}
+
+	mintRequests := make([]daotypes.MintRequest, 0, len(ready))
+	txIDs := make([]string, 0, len(ready))
+	for _, m := range ready {
+		mintRequests = append(mintRequests, daotypes.MintRequest{Beneficiary: m.beneficiary, Amount: m.amount, LiquidTxHash: m.liquidTxID})
+		txIDs = append(txIDs, m.liquidTxID)
+	}
+	batchCtx, span := tracer.Start(ctx, "MintPLMNTBatch", trace.WithAttributes(attrLiquidTxID.StringSlice(txIDs)))
+	result, err := r2p.pmClient.MintPLMNTBatch(batchCtx, mintRequests)
+	span.SetAttributes(attrPlanetmintTxHash.String(result.TxHash))
+	batchErr := err
+	if err == nil && result.Code != 0 {
+		batchErr = fmt.Errorf("mint tx %s rejected with code %d: %s", result.TxHash, result.Code, result.Log)
+	}
+	endSpan(span, batchErr)
+
+	errs := make(map[string][]error)
+	if batchErr != nil && len(ready) > 1 {
+		r2p.log(ctx).Error("msg", "batched mint failed, minting the deposits individually", "deposits", len(ready), "error", batchErr)
+		for i, m := range ready {
+			mintCtx := withConversion(ctx, *conversions[m.key])
+			errs[m.key] = append(errs[m.key], r2p.mintDeposit(mintCtx, m.beneficiary, deposits[i]))
+		}
+	} else {
+		for i, m := range ready {
+			mintCtx := withConversion(ctx, *conversions[m.key])
+			errs[m.key] = append(errs[m.key], r2p.applyMintResult(mintCtx, m.beneficiary, deposits[i], result, err))
+		}
+	}
+
+	for key, mintErrs := range errs {
+		conversion := conversions[key]
+		err := errors.Join(append(mintErrs, conversion.updateState())...)
+		conversion.LastError = ""
+		if err != nil {
+			conversion.LastError = err.Error()
+		}
+		if err = r2p.storeConversionRequest(conversion); err != nil {
+			r2p.log(withConversion(ctx, *conversion)).Error("msg", "storing conversion state failed", "error", err)
+		}
+	}
+}
+
+// lockMints locks the conversions and Liquid transactions of the mints. The locks are taken in order,
+// entries before transactions like everywhere else.
+func (r2p *R2PService) lockMints(mints []queuedMint) (unlock func()) {
+	keys := make([]string, 0, len(mints))
+	txIDs := make([]string, 0, len(mints))
+	for _, m := range mints {
+		keys = append(keys, m.key)
+		txIDs = append(txIDs, m.liquidTxID)
+	}
+	slices.Sort(keys)
+	slices.Sort(txIDs)
+
+	var unlocks []func()
+	for _, key := range slices.Compact(keys) {
+		unlocks = append(unlocks, r2p.entryLocks.lock(key))
+	}
+	for _, txID := range txIDs {
+		unlocks = append(unlocks, r2p.txLocks.lock(txID))
+	}
+	return func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+}
+
+// readyMints re-reads the conversions of the mints, as they may have changed since the mints were queued,
+// and returns the mints whose deposit is still waiting to be minted together with the deposits.
+func (r2p *R2PService) readyMints(ctx context.Context, mints []queuedMint) (conversions map[string]*ConversionRequest, ready []queuedMint, deposits []*types.Deposit) {
+	conversions = make(map[string]*ConversionRequest)
+	for _, m := range mints {
+		conversion, ok := conversions[m.key]
+		if !ok {
+			req, err := r2p.GetConversionRequest(m.key)
+			if err != nil {
+				r2p.log(ctx).Error("msg", "reading conversion failed", "liquid-address", m.key, "error", err)
+				continue
+			}
+			conversion = &req
+			conversions[m.key] = conversion
+		}
+		deposit := conversion.deposit(m.liquidTxID)
+		if deposit == nil || deposit.State != types.StateConfirmed || deposit.PLMNTAmount != m.amount {
+			r2p.log(withConversion(ctx, *conversion)).Info("msg", "mint changed since it was queued and is skipped", "txid", m.liquidTxID)
+			continue
+		}
+		ready = append(ready, m)
+		deposits = append(deposits, deposit)
+	}
+	return
+}
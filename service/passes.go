@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+)
+
+// pass tracks the runs of a periodic task. A task runs at most once at a time, ticks arriving while
+// the previous pass is still running are skipped. The task returns an error if it did not complete.
+type pass struct {
+	name    string
+	task    func(ctx context.Context) error
+	mu      sync.Mutex
+	running bool
+	status  types.PassStatus
+}
+
+func newPass(name string, task func(ctx context.Context) error) *pass {
+	return &pass{name: name, task: task}
+}
+
+// startPass runs the task of the pass in the background unless it is already running. Shutdown waits
+// for the pass to return.
+func (r2p *R2PService) startPass(p *pass) {
+	p.mu.Lock()
+	if p.running {
+		p.status.SkippedTicks++
+		p.mu.Unlock()
+		r2p.logger.Info("msg", "skipping tick, the previous pass is still running", "pass", p.name)
+		return
+	}
+	p.running = true
+	p.status.Running = true
+	started := time.Now()
+	p.status.LastStart = started.Unix()
+	p.mu.Unlock()
+
+	r2p.workers.Add(1)
+	go func() {
+		defer r2p.workers.Done()
+		ctx, cancel := r2p.passContext()
+		err := p.task(ctx)
+		cancel()
+
+		duration := time.Since(started)
+		p.mu.Lock()
+		p.running = false
+		p.status.Running = false
+		p.status.Passes++
+		p.status.LastDurationMs = duration.Milliseconds()
+		passDuration.WithLabelValues(p.name).Observe(duration.Seconds())
+		if err == nil {
+			p.status.LastSuccess = time.Now().Unix()
+		}
+		p.mu.Unlock()
+		if err != nil {
+			r2p.logger.Error("msg", "pass failed", "pass", p.name, "duration", duration.String(), "error", err)
+			return
+		}
+		r2p.logger.Debug("msg", "pass completed", "pass", p.name, "duration", duration.String())
+	}()
+}
+
+// passContext returns the context of a pass, it is cancelled on shutdown.
+func (r2p *R2PService) passContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-r2p.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+func (p *pass) getStatus() types.PassStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+// GetPassStatus returns the status of the periodic cleanup, conversion and reconciliation passes.
+func (r2p *R2PService) GetPassStatus() map[string]types.PassStatus {
+	return map[string]types.PassStatus{
+		r2p.cleanupPass.name:        r2p.cleanupPass.getStatus(),
+		r2p.conversionPass.name:     r2p.conversionPass.getStatus(),
+		r2p.reconciliationPass.name: r2p.reconciliationPass.getStatus(),
+	}
+}
+
+// entryLocks serializes the work on a single conversion request between the periodic passes and the
+// admin endpoints. Locks are dropped once nobody holds or waits for them.
+type entryLocks struct {
+	mu    sync.Mutex
+	locks map[string]*entryLock
+}
+
+type entryLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks the entry and returns the function to unlock it.
+func (el *entryLocks) lock(key string) (unlock func()) {
+	el.mu.Lock()
+	if el.locks == nil {
+		el.locks = make(map[string]*entryLock)
+	}
+	l, ok := el.locks[key]
+	if !ok {
+		l = &entryLock{}
+		el.locks[key] = l
+	}
+	l.refs++
+	el.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		el.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(el.locks, key)
+		}
+		el.mu.Unlock()
+	}
+}
@@ -1,18 +1,30 @@
 package service_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/planetmint/planetmint-go/util"
+	daotypes "github.com/planetmint/planetmint-go/x/dao/types"
+	elementstypes "github.com/rddl-network/elements-rpc/types"
 	log "github.com/rddl-network/go-utils/logger"
 	"github.com/rddl-network/rddl-2-plmnt-service/config"
 	"github.com/rddl-network/rddl-2-plmnt-service/service"
 	"github.com/rddl-network/rddl-2-plmnt-service/testutil"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/syndtr/goleveldb/leveldb"
+	leveldberrors "github.com/syndtr/goleveldb/leveldb/errors"
 	"github.com/syndtr/goleveldb/leveldb/storage"
 )
 
@@ -30,20 +42,1099 @@ func TestPeriodicCheck(t *testing.T) {
 		stdlog.Fatal(err)
 	}
 	defer db.Close()
-	r2p := service.NewR2PService(router, pmClientMock, eClientMock, db, log.GetLogger(log.DEBUG))
+	r2p := service.NewR2PService(router, pmClientMock, eClientMock, service.NewStaticRateProvider(100), db, log.GetLogger(log.DEBUG))
 
-	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil).AnyTimes()
-	pmClientMock.EXPECT().CheckMintRequest(gomock.Any()).Return(nil, nil).AnyTimes()
-	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil).AnyTimes()
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(types.TxResult{}, nil).AnyTimes()
 
 	var conversion service.ConversionRequest
 	conversion.ConfidentialAddress = "tlq1qqfz5fmd860877mm7ka7s5a3ryzeajd7xsamedk4cljtlla7tpzx3zux9sk6msuth78rtk7u4whn2nkxe8l9uyy9pcd9semy9m"
-	_, err = r2p.ExecutePotentialConversion(conversion)
+	eClientMock.EXPECT().GetTransaction(gomock.Any(), gomock.Any(), gomock.Any()).Return(paidTo(testutil.Deposit1Of1Tx, conversion.ConfidentialAddress), nil).AnyTimes()
+	err = r2p.ExecutePotentialConversion(context.Background(), conversion)
 	assert.NoError(t, err)
 }
 
+func TestConversionStates(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+
+	// funds seen but not yet confirmed
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil)
+	expectGetTransaction(eClientMock, testutil.Deposit1Of1Tx)
+	err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	res := getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateFundsDetected, res.State)
+	assert.Len(t, res.Deposits, 1)
+	assert.Equal(t, uint64(testutil.Deposit1Of1Tx.Confirmations), res.Deposits[0].Confirmations)
+	assert.Equal(t, testutil.Deposit1Of1Tx.TxID, res.Deposits[0].LiquidTxID)
+
+	// mint fails and is retried until maxMintAttempts is reached
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil).Times(3)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of1Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), gomock.Any()).Return(nil, nil).Times(3)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, gomock.Any(), testutil.Deposit1Of1Tx.TxID).Return(types.TxResult{}, errors.New("out of gas")).Times(3)
+	for i := 0; i < 2; i++ {
+		err = r2p.ExecutePotentialConversion(context.Background(), storedConversion(t, r2p, testutil.ConfidentialAddr))
+		assert.Error(t, err)
+		res = getConversion(t, router, testutil.ConfidentialAddr)
+		assert.Equal(t, types.StateConfirmed, res.State)
+		assert.Contains(t, res.LastError, "out of gas")
+	}
+	err = r2p.ExecutePotentialConversion(context.Background(), storedConversion(t, r2p, testutil.ConfidentialAddr))
+	assert.Error(t, err)
+	res = getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateFailed, res.State)
+	assert.Equal(t, types.StateFailed, res.Deposits[0].State)
+	assert.Equal(t, 3, res.Deposits[0].MintAttempts)
+}
+
+func TestMintConfirmation(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+	conversion.Timestamp = time.Now().Unix()
+
+	// mint broadcast
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil).Times(3)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of1Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), gomock.Any()).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(200), testutil.Deposit1Of1Tx.TxID).Return(testutil.MintTxResult, nil)
+	err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	res := getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateMintBroadcast, res.State)
+	assert.Equal(t, uint64(200), res.Deposits[0].PLMNTAmount)
+	assert.Equal(t, uint64(100), res.Deposits[0].ConversionRate)
+	assert.Equal(t, testutil.MintTxResult.TxHash, res.Deposits[0].PlanetmintTxHash)
+	assert.NotZero(t, res.Deposits[0].BroadcastAt)
+	assert.Empty(t, res.LastError)
+
+	// mint request not yet found on planetmint
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), gomock.Any()).Return(nil, nil)
+	pmClientMock.EXPECT().GetTxResult(gomock.Any(), testutil.MintTxResult.TxHash).Return(types.TxResult{}, false, nil)
+	err = r2p.ExecutePotentialConversion(context.Background(), storedConversion(t, r2p, testutil.ConfidentialAddr))
+	assert.NoError(t, err)
+	res = getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateMintBroadcast, res.State)
+
+	// mint request found on planetmint
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), gomock.Any()).Return(&daotypes.QueryGetMintRequestsByHashResponse{}, nil)
+	err = r2p.ExecutePotentialConversion(context.Background(), storedConversion(t, r2p, testutil.ConfidentialAddr))
+	assert.NoError(t, err)
+	res = getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateMintConfirmed, res.State)
+	assert.NotZero(t, res.Deposits[0].MintedAt)
+	states := make([]types.ConversionState, 0, len(res.History))
+	for _, transition := range res.History {
+		states = append(states, transition.State)
+	}
+	assert.Equal(t, []types.ConversionState{types.StateRegistered, types.StateConfirmed, types.StateMintBroadcast, types.StateMintConfirmed}, states)
+
+	// finished conversions take top-ups until their monitoring window passed and are archived afterwards
+	r2p.CleanupDB()
+	finished := storedConversion(t, r2p, testutil.ConfidentialAddr)
+	finished.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	assert.NoError(t, r2p.StoreConversionRequest(finished))
+	r2p.CleanupDB()
+	_, err = r2p.GetConversionRequest(testutil.ConfidentialAddr)
+	assert.ErrorIs(t, err, leveldberrors.ErrNotFound)
+	archived, err := r2p.GetArchivedConversion(testutil.ConfidentialAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, types.StateMintConfirmed, archived.State)
+	assert.Equal(t, testutil.PlanetmintAddress, archived.PlanetmintAddress)
+	assert.Len(t, archived.Deposits, 1)
+	assert.Equal(t, testutil.Deposit1Of1Tx.TxID, archived.Deposits[0].LiquidTxID)
+	assert.Equal(t, uint64(200000000), archived.Deposits[0].RDDLAmount)
+	assert.Equal(t, uint64(200), archived.Deposits[0].PLMNTAmount)
+}
+
+func TestMintRetry(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil).AnyTimes()
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of1Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of1Tx.TxID).Return(nil, nil).AnyTimes()
+
+	// the mint tx is rejected in CheckTx
+	rejected := types.TxResult{TxHash: "rejected", Code: 13, Log: "insufficient fee"}
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(200), testutil.Deposit1Of1Tx.TxID).Return(rejected, nil)
+	err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.ErrorContains(t, err, "insufficient fee")
+	res := getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateConfirmed, res.State)
+	assert.Equal(t, uint32(13), res.Deposits[0].PlanetmintTxCode)
+	assert.Zero(t, res.Deposits[0].BroadcastAt)
+
+	// the mint tx is accepted but fails in its block
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(200), testutil.Deposit1Of1Tx.TxID).Return(testutil.MintTxResult, nil)
+	err = r2p.ExecutePotentialConversion(context.Background(), storedConversion(t, r2p, testutil.ConfidentialAddr))
+	assert.NoError(t, err)
+	res = getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateMintBroadcast, res.State)
+	assert.Equal(t, uint32(0), res.Deposits[0].PlanetmintTxCode)
+
+	failed := testutil.MintTxResult
+	failed.Code = 11
+	failed.Log = "out of gas"
+	pmClientMock.EXPECT().GetTxResult(gomock.Any(), testutil.MintTxResult.TxHash).Return(failed, true, nil)
+	err = r2p.ExecutePotentialConversion(context.Background(), storedConversion(t, r2p, testutil.ConfidentialAddr))
+	assert.ErrorContains(t, err, "out of gas")
+	res = getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateConfirmed, res.State)
+	assert.Equal(t, uint32(11), res.Deposits[0].PlanetmintTxCode)
+
+	// the retry mints the amount of the first broadcast
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(200), testutil.Deposit1Of1Tx.TxID).Return(testutil.MintTxResult, nil)
+	err = r2p.ExecutePotentialConversion(context.Background(), storedConversion(t, r2p, testutil.ConfidentialAddr))
+	assert.NoError(t, err)
+	res = getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateMintBroadcast, res.State)
+	assert.Equal(t, 3, res.Deposits[0].MintAttempts)
+
+	// the mint tx is not included in time and the mint attempts are exhausted
+	pending := storedConversion(t, r2p, testutil.ConfidentialAddr)
+	pending.Deposits[0].BroadcastAt = time.Now().Add(-cfg.MintInclusionTimeout - time.Minute).Unix()
+	pmClientMock.EXPECT().GetTxResult(gomock.Any(), testutil.MintTxResult.TxHash).Return(types.TxResult{}, false, nil)
+	err = r2p.ExecutePotentialConversion(context.Background(), pending)
+	assert.ErrorContains(t, err, "not found on planetmint within")
+	res = getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateFailed, res.State)
+	assert.Equal(t, types.StateFailed, res.Deposits[0].State)
+}
+
+func TestDryRun(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+
+	// the simulated mint finishes the conversion without a planetmint tx
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of1Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of1Tx.TxID).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(200), testutil.Deposit1Of1Tx.TxID).Return(types.TxResult{Simulated: true, GasUsed: 85000}, nil)
+	err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+
+	res := getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateSimulated, res.State)
+	assert.Equal(t, types.StateSimulated, res.Deposits[0].State)
+	assert.Equal(t, uint64(200), res.Deposits[0].PLMNTAmount)
+	assert.Equal(t, uint64(85000), res.Deposits[0].SimulatedGas)
+	assert.Empty(t, res.Deposits[0].PlanetmintTxHash)
+	// the conversion is past its monitoring window and archived like a minted one
+	r2p.CleanupDB()
+	_, err = r2p.GetArchivedConversion(testutil.ConfidentialAddr)
+	assert.NoError(t, err)
+
+	// the remainder of a simulated mint does not stay in the ledger
+	fraction := paidTo(elementstypes.GetTransactionResult{TxID: testutil.Deposit2Of2Tx.TxID, Amount: map[string]float64{testutil.AcceptedAsset: 0.015}, Confirmations: cfg.Confirmations}, testutil.UnconfidentialAddr)
+	conversion.ConfidentialAddress = testutil.UnconfidentialAddr
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return([]elementstypes.ListReceivedByAddressResult{
+		{Address: testutil.UnconfidentialAddr, Amount: 0.015, TxIDs: []string{fraction.TxID}},
+	}, nil)
+	expectGetTransaction(eClientMock, fraction)
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), fraction.TxID).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(1), fraction.TxID).Return(types.TxResult{Simulated: true}, nil)
+	err = r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	res = getConversion(t, router, testutil.UnconfidentialAddr)
+	assert.Equal(t, types.StateSimulated, res.State)
+	assert.Equal(t, uint64(50000000), res.Deposits[0].Remainder)
+	remainders, err := r2p.GetRemainders()
+	assert.NoError(t, err)
+	assert.Empty(t, remainders)
+}
+
+func TestMintBatching(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.MintBatchSize = 2
+	defer func() { cfg.MintBatchSize = config.DefaultConfig().MintBatchSize }()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+	addresses := registerFundedConversions(t, r2p, eClientMock, 3)
+
+	// the three deposits are minted in a batch of two and a batch of one
+	var batchSizes []int
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), gomock.Any()).Return(nil, nil).Times(3)
+	pmClientMock.EXPECT().MintPLMNTBatch(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, mintRequests []daotypes.MintRequest) (types.TxResult, error) {
+		batchSizes = append(batchSizes, len(mintRequests))
+		for _, mintRequest := range mintRequests {
+			assert.Equal(t, testutil.PlanetmintAddress, mintRequest.Beneficiary)
+			assert.Equal(t, uint64(200), mintRequest.Amount)
+		}
+		return types.TxResult{TxHash: fmt.Sprintf("batch%d", len(batchSizes))}, nil
+	}).Times(2)
+	r2p.ConvertArrivedFunds()
+	assert.ElementsMatch(t, []int{2, 1}, batchSizes)
+
+	txHashes := make(map[string]int)
+	for _, address := range addresses {
+		res := getConversion(t, router, address)
+		assert.Equal(t, types.StateMintBroadcast, res.State)
+		assert.Equal(t, 1, res.Deposits[0].MintAttempts)
+		txHashes[res.Deposits[0].PlanetmintTxHash]++
+	}
+	assert.Len(t, txHashes, 2)
+}
+
+func TestMintBatchFallback(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.MintBatchSize = 10
+	defer func() { cfg.MintBatchSize = config.DefaultConfig().MintBatchSize }()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+	addresses := registerFundedConversions(t, r2p, eClientMock, 2)
+
+	// the batch fails because of one mint request, the other one is minted on its own
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), gomock.Any()).Return(nil, nil).Times(2)
+	pmClientMock.EXPECT().MintPLMNTBatch(gomock.Any(), gomock.Len(2)).Return(types.TxResult{TxHash: "batch", Code: 4, Log: "unauthorized"}, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(200), "tx"+addresses[0]).Return(types.TxResult{TxHash: "single", Code: 4, Log: "unauthorized"}, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(200), "tx"+addresses[1]).Return(types.TxResult{TxHash: "single"}, nil)
+	r2p.ConvertArrivedFunds()
+
+	res := getConversion(t, router, addresses[0])
+	assert.Equal(t, types.StateConfirmed, res.State)
+	assert.Contains(t, res.LastError, "unauthorized")
+	res = getConversion(t, router, addresses[1])
+	assert.Equal(t, types.StateMintBroadcast, res.State)
+	assert.Equal(t, "single", res.Deposits[0].PlanetmintTxHash)
+	assert.Empty(t, res.LastError)
+}
+
+func TestMintBatchFailedInBlock(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.MintBatchSize = 10
+	defer func() { cfg.MintBatchSize = config.DefaultConfig().MintBatchSize }()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+	addresses := registerFundedConversions(t, r2p, eClientMock, 2)
+
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	pmClientMock.EXPECT().MintPLMNTBatch(gomock.Any(), gomock.Len(2)).Return(types.TxResult{TxHash: "batch"}, nil)
+	r2p.ConvertArrivedFunds()
+
+	// the batch passes CheckTx but fails in its block, so the mints are retried one by one
+	pmClientMock.EXPECT().GetTxResult(gomock.Any(), "batch").Return(types.TxResult{TxHash: "batch", Code: 11, Log: "out of gas"}, true, nil).Times(2)
+	r2p.ConvertArrivedFunds()
+	for _, address := range addresses {
+		res := getConversion(t, router, address)
+		assert.Equal(t, types.StateConfirmed, res.State)
+		assert.True(t, res.Deposits[0].MintSeparately)
+	}
+
+	for _, address := range addresses {
+		pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(200), "tx"+address).Return(types.TxResult{TxHash: "single" + address}, nil)
+	}
+	r2p.ConvertArrivedFunds()
+	for _, address := range addresses {
+		res := getConversion(t, router, address)
+		assert.Equal(t, types.StateMintBroadcast, res.State)
+		assert.Equal(t, "single"+address, res.Deposits[0].PlanetmintTxHash)
+	}
+}
+
+// registerFundedConversions registers n conversions, each of which received a confirmed deposit of 2 RDDL
+// with the transaction id "tx<address>".
+func registerFundedConversions(t *testing.T, r2p *service.R2PService, eClientMock *testutil.MockIElementsClient, n int) (addresses []string) {
+	t.Helper()
+	cfg := config.GetConfig()
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(n)
+	for i := range n {
+		var conversion service.ConversionRequest
+		conversion.ConfidentialAddress = fmt.Sprintf("address%d", i)
+		conversion.PlanetmintAddress = testutil.PlanetmintAddress
+		conversion.Timestamp = time.Now().Unix()
+		assert.NoError(t, r2p.ExecutePotentialConversion(context.Background(), conversion))
+		addresses = append(addresses, conversion.ConfidentialAddress)
+	}
+
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, params []string) ([]elementstypes.ListReceivedByAddressResult, error) {
+		address := strings.Trim(params[3], `"`)
+		return []elementstypes.ListReceivedByAddressResult{{Address: address, Amount: 2, TxIDs: []string{"tx" + address}}}, nil
+	}).AnyTimes()
+	eClientMock.EXPECT().GetTransaction(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, params []string) (elementstypes.GetTransactionResult, error) {
+		txID := strings.Trim(params[0], `"`)
+		tx := elementstypes.GetTransactionResult{TxID: txID, Amount: map[string]float64{testutil.AcceptedAsset: 2}, Confirmations: cfg.Confirmations}
+		return paidTo(tx, strings.TrimPrefix(txID, "tx")), nil
+	}).AnyTimes()
+	return
+}
+
+func TestMultipleDeposits(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+
+	// first deposit is confirmed and minted, the top-up is still unconfirmed
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray2Tx, nil).Times(2)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of2Tx, cfg.Confirmations))
+	expectGetTransaction(eClientMock, testutil.Deposit2Of2Tx)
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of2Tx.TxID).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(150), testutil.Deposit1Of2Tx.TxID).Return(types.TxResult{}, nil)
+	err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	res := getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateFundsDetected, res.State)
+	assert.Len(t, res.Deposits, 2)
+	assert.Equal(t, types.StateMintBroadcast, res.Deposits[0].State)
+	assert.Equal(t, types.StateFundsDetected, res.Deposits[1].State)
+
+	// the top-up gets confirmed and minted separately, the first mint is found on planetmint
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit2Of2Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of2Tx.TxID).Return(&daotypes.QueryGetMintRequestsByHashResponse{}, nil)
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit2Of2Tx.TxID).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(50), testutil.Deposit2Of2Tx.TxID).Return(types.TxResult{}, nil)
+	err = r2p.ExecutePotentialConversion(context.Background(), storedConversion(t, r2p, testutil.ConfidentialAddr))
+	assert.NoError(t, err)
+	res = getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateMintBroadcast, res.State)
+	assert.Equal(t, types.StateMintConfirmed, res.Deposits[0].State)
+	assert.Equal(t, types.StateMintBroadcast, res.Deposits[1].State)
+}
+
+func TestTopUpAfterMint(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+	conversion.Timestamp = time.Now().Unix()
+	conversion.State = types.StateRegistered
+	assert.NoError(t, r2p.StoreConversionRequest(conversion))
+
+	// the first deposit is minted and confirmed
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return([]elementstypes.ListReceivedByAddressResult{
+		{Address: testutil.ConfidentialAddr, Amount: 1.5, TxIDs: []string{testutil.Deposit1Of2Tx.TxID}},
+	}, nil).Times(2)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of2Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of2Tx.TxID).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(150), testutil.Deposit1Of2Tx.TxID).Return(testutil.MintTxResult, nil)
+	r2p.ConvertArrivedFunds()
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of2Tx.TxID).Return(&daotypes.QueryGetMintRequestsByHashResponse{}, nil)
+	r2p.ConvertArrivedFunds()
+	assert.Equal(t, types.StateMintConfirmed, getConversion(t, router, testutil.ConfidentialAddr).State)
+
+	// the finished conversion stays active within its monitoring window
+	r2p.CleanupDB()
+	_, err := r2p.GetConversionRequest(testutil.ConfidentialAddr)
+	assert.NoError(t, err)
+
+	// the top-up is detected and minted as well
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray2Tx, nil)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit2Of2Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit2Of2Tx.TxID).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(50), testutil.Deposit2Of2Tx.TxID).Return(testutil.MintTxResult, nil)
+	r2p.ConvertArrivedFunds()
+	res := getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateMintBroadcast, res.State)
+	assert.Len(t, res.Deposits, 2)
+	assert.Equal(t, types.StateMintConfirmed, res.Deposits[0].State)
+	assert.Equal(t, types.StateMintBroadcast, res.Deposits[1].State)
+}
+
+func TestDepositAmountMismatch(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.AdminToken = "secret"
+	defer func() { cfg.AdminToken = "" }()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+
+	// the wallet reports a different amount for the tx than for the address
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil).Times(2)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of2Tx, cfg.Confirmations))
+	err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.Error(t, err)
+	res := getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateNeedsReview, res.State)
+	assert.Equal(t, types.StateNeedsReview, res.Deposits[0].State)
+
+	// an approved deposit is minted with its recorded amount
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/admin/conversion/"+testutil.ConfidentialAddr+"/approve", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of2Tx.TxID).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(150), testutil.Deposit1Of2Tx.TxID).Return(types.TxResult{}, nil)
+	err = r2p.ExecutePotentialConversion(context.Background(), storedConversion(t, r2p, testutil.ConfidentialAddr))
+	assert.NoError(t, err)
+	res = getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateMintBroadcast, res.State)
+}
+
+func TestDepositPayingSeveralAddresses(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.AdminToken = "secret"
+	defer func() { cfg.AdminToken = "" }()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	// a batched payout pays two receive addresses with one tx
+	tx := confirmed(testutil.Deposit1Of1Tx, cfg.Confirmations)
+	tx.Details = []elementstypes.GetTransactionDetailsResult{
+		{Address: testutil.ConfidentialAddr, Amount: 1.5, Category: "receive"},
+		{Address: testutil.UnconfidentialAddr, Amount: 0.5, Category: "receive"},
+	}
+	received := map[string]float64{testutil.ConfidentialAddr: 1.5, testutil.UnconfidentialAddr: 0.5}
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, params []string) ([]elementstypes.ListReceivedByAddressResult, error) {
+		address := strings.Trim(params[3], `"`)
+		return []elementstypes.ListReceivedByAddressResult{{Address: address, Amount: received[address], TxIDs: []string{tx.TxID}}}, nil
+	}).Times(2)
+	eClientMock.EXPECT().GetTransaction(gomock.Any(), gomock.Any(), gomock.Any()).Return(tx, nil).Times(2)
+
+	// every conversion records the amount paid to its address, the tx can only be minted once on planetmint
+	for address, amount := range received {
+		var conversion service.ConversionRequest
+		conversion.ConfidentialAddress = address
+		conversion.PlanetmintAddress = testutil.PlanetmintAddress
+		conversion.RefundAddress = testutil.GetNewAddress
+		err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+		assert.NoError(t, err)
+		res := getConversion(t, router, address)
+		assert.Equal(t, types.StateNeedsReview, res.State)
+		assert.Equal(t, types.StateNeedsReview, res.Deposits[0].State)
+		assert.Equal(t, util.RDDLToken2Uint(amount), res.Deposits[0].RDDLAmount)
+	}
+
+	// the refunds send back the amount paid to every address once
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), tx.TxID).Return(nil, nil).Times(2)
+	for address, amount := range received {
+		eClientMock.EXPECT().SendToAddress(gomock.Any(), gomock.Any(), []string{
+			`"` + testutil.GetNewAddress + `"`, util.UintValueToRDDLTokenString(util.RDDLToken2Uint(amount)), `""`, `""`, "false", "true", "null", `"unset"`, "false", `"` + cfg.AcceptedAsset + `"`,
+		}).Return("refund"+address, nil)
+		w := httptest.NewRecorder()
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/admin/conversion/"+address+"/refund", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, types.StateRefunded, getConversion(t, router, address).State)
+	}
+}
+
+func TestRemainderLedger(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	dust := paidTo(elementstypes.GetTransactionResult{TxID: testutil.Deposit1Of2Tx.TxID, Amount: map[string]float64{testutil.AcceptedAsset: 0.005}, Confirmations: cfg.Confirmations}, testutil.ConfidentialAddr)
+	topUp := paidTo(elementstypes.GetTransactionResult{TxID: testutil.Deposit2Of2Tx.TxID, Amount: map[string]float64{testutil.AcceptedAsset: 0.015}, Confirmations: cfg.Confirmations}, testutil.UnconfidentialAddr)
+
+	// a deposit worth half a PLMNT is credited to the beneficiary instead of being minted
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return([]elementstypes.ListReceivedByAddressResult{
+		{Address: testutil.ConfidentialAddr, Amount: 0.005, TxIDs: []string{dust.TxID}},
+	}, nil)
+	expectGetTransaction(eClientMock, dust)
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), dust.TxID).Return(nil, nil)
+	err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	res := getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateCredited, res.State)
+	assert.Equal(t, uint64(0), res.Deposits[0].PLMNTAmount)
+	assert.Equal(t, uint64(50000000), res.Deposits[0].Remainder)
+	remainders, err := r2p.GetRemainders()
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Remainder{{Beneficiary: testutil.PlanetmintAddress, Remainder: 50000000}}, remainders)
+
+	// the ledger lists the beneficiaries, so it needs the admin token
+	cfg.AdminToken = "secret"
+	defer func() { cfg.AdminToken = "" }()
+	for token, code := range map[string]int{"": http.StatusUnauthorized, "secret": http.StatusOK} {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/admin/remainders", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, code, w.Code)
+	}
+
+	// the credit is added to the next conversion of the beneficiary
+	conversion.ConfidentialAddress = testutil.UnconfidentialAddr
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return([]elementstypes.ListReceivedByAddressResult{
+		{Address: testutil.UnconfidentialAddr, Amount: 0.015, TxIDs: []string{topUp.TxID}},
+	}, nil)
+	expectGetTransaction(eClientMock, topUp)
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), topUp.TxID).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(2), topUp.TxID).Return(types.TxResult{}, nil)
+	err = r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	res = getConversion(t, router, testutil.UnconfidentialAddr)
+	assert.Equal(t, uint64(50000000), res.Deposits[0].RemainderCredit)
+	assert.Equal(t, uint64(0), res.Deposits[0].Remainder)
+	remainders, err = r2p.GetRemainders()
+	assert.NoError(t, err)
+	assert.Empty(t, remainders)
+}
+
+func TestRemainderLedgerRevert(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	dust := paidTo(elementstypes.GetTransactionResult{TxID: testutil.Deposit1Of2Tx.TxID, Amount: map[string]float64{testutil.AcceptedAsset: 0.005}, Confirmations: cfg.Confirmations}, testutil.ConfidentialAddr)
+	topUp := paidTo(elementstypes.GetTransactionResult{TxID: testutil.Deposit2Of2Tx.TxID, Amount: map[string]float64{testutil.AcceptedAsset: 0.015}, Confirmations: cfg.Confirmations}, testutil.UnconfidentialAddr)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return([]elementstypes.ListReceivedByAddressResult{
+		{Address: testutil.ConfidentialAddr, Amount: 0.005, TxIDs: []string{dust.TxID}},
+	}, nil)
+	expectGetTransaction(eClientMock, dust)
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), dust.TxID).Return(nil, nil)
+	err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+
+	// the credit is consumed by the next conversion, whose mint is rejected until the attempts are exhausted
+	conversion.ConfidentialAddress = testutil.UnconfidentialAddr
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return([]elementstypes.ListReceivedByAddressResult{
+		{Address: testutil.UnconfidentialAddr, Amount: 0.015, TxIDs: []string{topUp.TxID}},
+	}, nil).AnyTimes()
+	eClientMock.EXPECT().GetTransaction(gomock.Any(), gomock.Any(), []string{`"` + topUp.TxID + `"`}).Return(topUp, nil).AnyTimes()
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), topUp.TxID).Return(nil, nil).AnyTimes()
+	rejected := types.TxResult{TxHash: "rejected", Code: 13, Log: "insufficient fee"}
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(2), topUp.TxID).Return(rejected, nil).Times(3)
+	err = r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.ErrorContains(t, err, "insufficient fee")
+	remainders, err := r2p.GetRemainders()
+	assert.NoError(t, err)
+	assert.Empty(t, remainders)
+	for range 2 {
+		_ = r2p.ExecutePotentialConversion(context.Background(), storedConversion(t, r2p, testutil.UnconfidentialAddr))
+	}
+	res := getConversion(t, router, testutil.UnconfidentialAddr)
+	assert.Equal(t, types.StateFailed, res.Deposits[0].State)
+
+	// the failed deposit gives the credit back to the beneficiary, once
+	expected := []types.Remainder{{Beneficiary: testutil.PlanetmintAddress, Remainder: 50000000}}
+	remainders, err = r2p.GetRemainders()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, remainders)
+	err = r2p.StoreConversionRequest(storedConversion(t, r2p, testutil.UnconfidentialAddr))
+	assert.NoError(t, err)
+	remainders, err = r2p.GetRemainders()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, remainders)
+}
+
+func TestDepositLimits(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.MinDeposit = 1
+	cfg.MaxDeposit = 1.5
+	cfg.AdminToken = "secret"
+	defer func() {
+		cfg.MinDeposit = 0
+		cfg.MaxDeposit = 0
+		cfg.AdminToken = ""
+	}()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	// deposits below the minimum are flagged as dust and not minted
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.UnconfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return([]elementstypes.ListReceivedByAddressResult{
+		{Address: testutil.UnconfidentialAddr, Amount: 0.5, TxIDs: []string{testutil.Deposit2Of2Tx.TxID}},
+	}, nil)
+	expectGetTransaction(eClientMock, paidTo(confirmed(testutil.Deposit2Of2Tx, cfg.Confirmations), testutil.UnconfidentialAddr))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit2Of2Tx.TxID).Return(nil, nil)
+	err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	res := getConversion(t, router, testutil.UnconfidentialAddr)
+	assert.Equal(t, types.StateDust, res.State)
+	assert.Equal(t, types.StateDust, res.Deposits[0].State)
+
+	// deposits above the maximum need to be approved before they are minted
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil).Times(2)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of1Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of1Tx.TxID).Return(nil, nil).Times(2)
+	err = r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	res = getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateNeedsReview, res.State)
+	assert.Equal(t, types.StateNeedsReview, res.Deposits[0].State)
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/admin/conversion/"+testutil.ConfidentialAddr+"/approve", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	res = getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateConfirmed, res.State)
+	assert.True(t, res.Deposits[0].Approved)
+
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(200), testutil.Deposit1Of1Tx.TxID).Return(types.TxResult{}, nil)
+	err = r2p.ExecutePotentialConversion(context.Background(), storedConversion(t, r2p, testutil.ConfidentialAddr))
+	assert.NoError(t, err)
+	res = getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateMintBroadcast, res.State)
+}
+
+func TestRefund(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.MinDeposit = 1
+	cfg.AdminToken = "secret"
+	defer func() {
+		cfg.MinDeposit = 0
+		cfg.AdminToken = ""
+	}()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	refund := func(address string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/admin/conversion/"+address+"/refund", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+	conversion.RefundAddress = testutil.UnconfidentialAddr
+
+	// nothing to refund while the conversion waits for funds
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+	err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, refund(testutil.ConfidentialAddr).Code)
+
+	// dust gets refunded to the refund address
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return([]elementstypes.ListReceivedByAddressResult{
+		{Address: testutil.ConfidentialAddr, Amount: 0.5, TxIDs: []string{testutil.Deposit2Of2Tx.TxID}},
+	}, nil)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit2Of2Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit2Of2Tx.TxID).Return(nil, nil).Times(2)
+	err = r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	assert.Equal(t, types.StateDust, getConversion(t, router, testutil.ConfidentialAddr).State)
+
+	eClientMock.EXPECT().SendToAddress(gomock.Any(), gomock.Any(), []string{
+		`"` + testutil.UnconfidentialAddr + `"`, "0.50000000", `""`, `""`, "false", "true", "null", `"unset"`, "false", `"` + cfg.AcceptedAsset + `"`,
+	}).Return("refundTxID", nil)
+	assert.Equal(t, http.StatusOK, refund(testutil.ConfidentialAddr).Code)
+	res := getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateRefunded, res.State)
+	assert.Equal(t, types.StateRefunded, res.Deposits[0].State)
+	assert.Equal(t, "refundTxID", res.Deposits[0].RefundTxID)
+
+	// refunded conversions are archived once their monitoring window passed
+	r2p.CleanupDB()
+	_, err = r2p.GetArchivedConversion(testutil.ConfidentialAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, refund(testutil.ConfidentialAddr).Code)
+
+	// conversions without a refund address keep their funds until an operator steps in
+	conversion.ConfidentialAddress = testutil.UnconfidentialAddr
+	conversion.RefundAddress = ""
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return([]elementstypes.ListReceivedByAddressResult{
+		{Address: testutil.UnconfidentialAddr, Amount: 0.5, TxIDs: []string{testutil.Deposit2Of2Tx.TxID}},
+	}, nil)
+	expectGetTransaction(eClientMock, paidTo(confirmed(testutil.Deposit2Of2Tx, cfg.Confirmations), testutil.UnconfidentialAddr))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit2Of2Tx.TxID).Return(nil, nil)
+	err = r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, refund(testutil.UnconfidentialAddr).Code)
+}
+
+func TestRefundMintedDeposit(t *testing.T) {
+	r2p, _, pmClientMock, _ := setupR2PService(t)
+
+	// a deposit that got minted despite the failed mint attempts is not refunded
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+	conversion.RefundAddress = testutil.UnconfidentialAddr
+	conversion.State = types.StateFailed
+	conversion.Deposits = []types.Deposit{{LiquidTxID: testutil.Deposit1Of1Tx.TxID, Confirmations: uint64(config.GetConfig().Confirmations), RDDLAmount: 200000000, State: types.StateFailed}}
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of1Tx.TxID).Return(&daotypes.QueryGetMintRequestsByHashResponse{}, nil)
+	err := r2p.RefundConversion(context.Background(), &conversion)
+	assert.NoError(t, err)
+	assert.Equal(t, types.StateMintConfirmed, conversion.State)
+	assert.Empty(t, conversion.Deposits[0].RefundTxID)
+}
+
+func TestLateDeposits(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.AdminToken = "secret"
+	defer func() {
+		cfg.AdminToken = ""
+		cfg.ConvertLateDeposits = false
+	}()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	admin := func(method string, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequestWithContext(context.Background(), method, target, nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// register two conversions whose monitoring window passed
+	timestamp := time.Now().Add(-13 * time.Hour).Unix()
+	for _, address := range []string{testutil.ConfidentialAddr, testutil.UnconfidentialAddr} {
+		var conversion service.ConversionRequest
+		conversion.ConfidentialAddress = address
+		conversion.PlanetmintAddress = testutil.PlanetmintAddress
+		conversion.Timestamp = timestamp
+		eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+		err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+		assert.NoError(t, err)
+	}
+
+	// only the conversion without funds expires, the other one is queued as late deposit
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, params []string) ([]elementstypes.ListReceivedByAddressResult, error) {
+		if params[3] == `"`+testutil.ConfidentialAddr+`"` {
+			return testutil.ReceivedTxByAddressArray1Tx, nil
+		}
+		return nil, nil
+	}).Times(2)
+	expectGetTransaction(eClientMock, testutil.Deposit1Of1Tx)
+	r2p.CleanupDB()
+	res := getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateLateDeposit, res.State)
+	assert.Len(t, res.Deposits, 1)
+	assert.Equal(t, types.StateFundsDetected, res.Deposits[0].State)
+	assert.Equal(t, types.StateExpired, getConversion(t, router, testutil.UnconfidentialAddr).State)
+
+	// late deposits are not converted unless configured or approved
+	r2p.ConvertArrivedFunds()
+	w := admin(http.MethodGet, "/admin/late-deposits")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var lateDeposits []types.ConversionResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &lateDeposits))
+	assert.Len(t, lateDeposits, 1)
+	assert.Equal(t, testutil.ConfidentialAddr, lateDeposits[0].LiquidAddress)
+
+	cfg.ConvertLateDeposits = true
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of1Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of1Tx.TxID).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(200), testutil.Deposit1Of1Tx.TxID).Return(types.TxResult{}, nil)
+	r2p.ConvertArrivedFunds()
+	assert.Equal(t, types.StateMintBroadcast, getConversion(t, router, testutil.ConfidentialAddr).State)
+	w = admin(http.MethodGet, "/admin/late-deposits")
+	assert.Equal(t, "[]", w.Body.String())
+}
+
+func TestExpiryInConversionPass(t *testing.T) {
+	r2p, router, _, eClientMock := setupR2PService(t)
+
+	timestamp := time.Now().Add(-13 * time.Hour).Unix()
+	for _, address := range []string{testutil.ConfidentialAddr, testutil.UnconfidentialAddr} {
+		var conversion service.ConversionRequest
+		conversion.ConfidentialAddress = address
+		conversion.PlanetmintAddress = testutil.PlanetmintAddress
+		conversion.Timestamp = timestamp
+		conversion.State = types.StateRegistered
+		assert.NoError(t, r2p.StoreConversionRequest(conversion))
+	}
+
+	// the conversion pass expires conversions past their monitoring window instead of converting their funds
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, params []string) ([]elementstypes.ListReceivedByAddressResult, error) {
+		if params[3] == `"`+testutil.ConfidentialAddr+`"` {
+			return testutil.ReceivedTxByAddressArray1Tx, nil
+		}
+		return nil, nil
+	}).Times(2)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of1Tx, config.GetConfig().Confirmations))
+	r2p.ConvertArrivedFunds()
+	assert.Equal(t, types.StateLateDeposit, getConversion(t, router, testutil.ConfidentialAddr).State)
+	assert.Equal(t, types.StateExpired, getConversion(t, router, testutil.UnconfidentialAddr).State)
+	r2p.CleanupDB()
+	_, err := r2p.GetArchivedConversion(testutil.UnconfidentialAddr)
+	assert.NoError(t, err)
+}
+
+func TestPruneArchive(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, _, _, _ := setupR2PService(t)
+
+	finishedAt := map[string]time.Time{
+		testutil.ConfidentialAddr:   time.Now().Add(-cfg.ArchiveRetention - 24*time.Hour),
+		testutil.UnconfidentialAddr: time.Now().Add(-time.Hour),
+	}
+	for address, finished := range finishedAt {
+		var conversion service.ConversionRequest
+		conversion.ConfidentialAddress = address
+		conversion.PlanetmintAddress = testutil.PlanetmintAddress
+		conversion.Timestamp = finished.Add(-time.Hour).Unix()
+		conversion.ExpiresAt = finished.Unix()
+		conversion.State = types.StateExpired
+		conversion.History = []types.StateTransition{{State: types.StateExpired, Timestamp: finished.Unix()}}
+		assert.NoError(t, r2p.StoreConversionRequest(conversion))
+	}
+
+	// the cleanup pass archives both conversions and prunes the one finished before the retention period
+	r2p.CleanupDB()
+	_, err := r2p.GetArchivedConversion(testutil.ConfidentialAddr)
+	assert.ErrorIs(t, err, leveldberrors.ErrNotFound)
+	_, err = r2p.GetConversionRequest(testutil.ConfidentialAddr)
+	assert.ErrorIs(t, err, leveldberrors.ErrNotFound)
+	archived, err := r2p.GetArchivedConversion(testutil.UnconfidentialAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, types.StateExpired, archived.State)
+}
+
+func TestDepositWithinWindowAtExpiry(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+	conversion.Timestamp = time.Now().Add(-13 * time.Hour).Unix()
+	conversion.State = types.StateRegistered
+	assert.NoError(t, r2p.StoreConversionRequest(conversion))
+
+	// the deposit arrived within the monitoring window but after the last conversion pass, it is converted
+	// instead of being queued as a late deposit
+	deposit := confirmed(testutil.Deposit1Of1Tx, cfg.Confirmations)
+	deposit.TimeReceived = time.Now().Add(-12*time.Hour - time.Minute).Unix()
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil).Times(2)
+	expectGetTransaction(eClientMock, deposit)
+	expectGetTransaction(eClientMock, deposit)
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of1Tx.TxID).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(200), testutil.Deposit1Of1Tx.TxID).Return(testutil.MintTxResult, nil)
+	r2p.ConvertArrivedFunds()
+	res := getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateMintBroadcast, res.State)
+	for _, transition := range res.History {
+		assert.NotEqual(t, types.StateLateDeposit, transition.State)
+	}
+}
+
+func TestApproveLateDeposit(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.AdminToken = "secret"
+	defer func() { cfg.AdminToken = "" }()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+	conversion.Timestamp = time.Now().Add(-13 * time.Hour).Unix()
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+	err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil)
+	expectGetTransaction(eClientMock, testutil.Deposit1Of1Tx)
+	r2p.CleanupDB()
+
+	// approving a late deposit converts it right away
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of1Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of1Tx.TxID).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), testutil.PlanetmintAddress, uint64(200), testutil.Deposit1Of1Tx.TxID).Return(types.TxResult{}, nil)
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/admin/conversion/"+testutil.ConfidentialAddr+"/approve", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res types.ConversionResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, types.StateMintBroadcast, res.State)
+}
+
+func TestRefundLateDeposit(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.AdminToken = "secret"
+	cfg.AutoRefund = true
+	defer func() {
+		cfg.AdminToken = ""
+		cfg.AutoRefund = false
+	}()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+	conversion.RefundAddress = testutil.UnconfidentialAddr
+	conversion.Timestamp = time.Now().Add(-13 * time.Hour).Unix()
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+	err := r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil)
+	expectGetTransaction(eClientMock, testutil.Deposit1Of1Tx)
+	r2p.CleanupDB()
+
+	// late deposits are not refunded automatically, they wait for an operator
+	r2p.ConvertArrivedFunds()
+	assert.Equal(t, types.StateLateDeposit, getConversion(t, router, testutil.ConfidentialAddr).State)
+
+	refund := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/admin/conversion/"+testutil.ConfidentialAddr+"/refund", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// the deposit may still be conflicted, so it is not refunded before it has enough confirmations
+	expectGetTransaction(eClientMock, testutil.Deposit1Of1Tx)
+	w := refund()
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "cannot be refunded yet")
+	assert.Equal(t, types.StateLateDeposit, getConversion(t, router, testutil.ConfidentialAddr).State)
+
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of1Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of1Tx.TxID).Return(nil, nil)
+	eClientMock.EXPECT().SendToAddress(gomock.Any(), gomock.Any(), []string{
+		`"` + testutil.UnconfidentialAddr + `"`, "2.00000000", `""`, `""`, "false", "true", "null", `"unset"`, "false", `"` + cfg.AcceptedAsset + `"`,
+	}).Return("refundTxID", nil)
+	assert.Equal(t, http.StatusOK, refund().Code)
+	res := getConversion(t, router, testutil.ConfidentialAddr)
+	assert.Equal(t, types.StateRefunded, res.State)
+	assert.Equal(t, types.StateRefunded, res.Deposits[0].State)
+	assert.Equal(t, "refundTxID", res.Deposits[0].RefundTxID)
+}
+
+func TestStateTransition(t *testing.T) {
+	var conversion service.ConversionRequest
+	conversion.State = types.StateRegistered
+
+	assert.NoError(t, conversion.Transition(types.StateFundsDetected))
+	assert.NoError(t, conversion.Transition(types.StateFundsDetected))
+	assert.Error(t, conversion.Transition(types.StateMintConfirmed))
+	assert.Equal(t, types.StateFundsDetected, conversion.State)
+	assert.NoError(t, conversion.Transition(types.StateConfirmed))
+	assert.NoError(t, conversion.Transition(types.StateFailed))
+	assert.Error(t, conversion.Transition(types.StateConfirmed))
+	// failed conversions can still be refunded
+	assert.False(t, service.IsTerminal(conversion.State))
+	assert.NoError(t, conversion.Transition(types.StateRefunded))
+	// finished conversions take top-ups until they expire
+	assert.False(t, service.IsTerminal(conversion.State))
+	assert.NoError(t, conversion.Transition(types.StateFundsDetected))
+	assert.True(t, service.IsTerminal(types.StateExpired))
+	assert.Len(t, conversion.History, 5)
+}
+
+func getConversion(t *testing.T, router *gin.Engine, liquidAddress string) (res types.ConversionResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/conversion/"+liquidAddress, nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = json.Unmarshal(w.Body.Bytes(), &res)
+	assert.NoError(t, err)
+	return
+}
+
 func TestConversion(t *testing.T) {
 	convertedAmount := util.RDDLToken2Uint(570330.47944743)
-	plmntAmount := service.GetConversion(convertedAmount)
+	plmntAmount := service.GetConversion(convertedAmount, 100)
 	assert.Equal(t, uint64(57033047), plmntAmount)
+
+	plmntAmount, remainder := service.GetConversionWithRemainder(convertedAmount, 100, 0)
+	assert.Equal(t, uint64(57033047), plmntAmount)
+	assert.Equal(t, uint64(94474300), remainder)
+
+	plmntAmount, remainder = service.GetConversionWithRemainder(convertedAmount, 100, 5525700)
+	assert.Equal(t, uint64(57033048), plmntAmount)
+	assert.Equal(t, uint64(0), remainder)
+}
+
+func storedConversion(t *testing.T, r2p *service.R2PService, liquidAddress string) (conversion service.ConversionRequest) {
+	t.Helper()
+	conversion, err := r2p.GetConversionRequest(liquidAddress)
+	assert.NoError(t, err)
+	return
+}
+
+func setupR2PService(t testing.TB) (r2p *service.R2PService, router *gin.Engine, pmClientMock *testutil.MockIPlanetmintClient, eClientMock *testutil.MockIElementsClient) {
+	t.Helper()
+	router = gin.Default()
+	ctrl := gomock.NewController(t)
+	pmClientMock = testutil.NewMockIPlanetmintClient(ctrl)
+	eClientMock = testutil.NewMockIElementsClient(ctrl)
+
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		stdlog.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	r2p = service.NewR2PService(router, pmClientMock, eClientMock, service.NewStaticRateProvider(100), db, log.GetLogger(log.DEBUG))
+	return
+}
+
+func expectGetTransaction(eClientMock *testutil.MockIElementsClient, tx elementstypes.GetTransactionResult) {
+	eClientMock.EXPECT().GetTransaction(gomock.Any(), gomock.Any(), []string{`"` + tx.TxID + `"`}).Return(tx, nil)
+}
+
+func confirmed(tx elementstypes.GetTransactionResult, confirmations int64) elementstypes.GetTransactionResult {
+	tx.Confirmations = confirmations
+	return tx
+}
+
+// paidTo returns the tx paying its amount of the accepted asset to the address.
+func paidTo(tx elementstypes.GetTransactionResult, address string) elementstypes.GetTransactionResult {
+	tx.Details = []elementstypes.GetTransactionDetailsResult{{Address: address, Amount: tx.Amount[testutil.AcceptedAsset], Category: "receive"}}
+	return tx
+}
+
+// BenchmarkConvertArrivedFunds measures a conversion pass over 10k open receive addresses with an
+// elements RPC latency of 100µs per call.
+func BenchmarkConvertArrivedFunds(b *testing.B) {
+	const entries = 10000
+	cfg := config.GetConfig()
+	defer func() { cfg.ConversionWorkers = config.DefaultConfig().ConversionWorkers }()
+
+	for _, workers := range []int{1, 8, 32} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			cfg.ConversionWorkers = workers
+			r2p, _, _, eClientMock := setupR2PService(b)
+			eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(entries)
+			for i := range entries {
+				var conversion service.ConversionRequest
+				conversion.ConfidentialAddress = fmt.Sprintf("address%05d", i)
+				conversion.PlanetmintAddress = testutil.PlanetmintAddress
+				conversion.Timestamp = time.Now().Unix()
+				if err := r2p.ExecutePotentialConversion(context.Background(), conversion); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, _ []string) ([]elementstypes.ListReceivedByAddressResult, error) {
+				time.Sleep(100 * time.Microsecond)
+				return nil, nil
+			}).AnyTimes()
+			b.ResetTimer()
+			for range b.N {
+				r2p.ConvertArrivedFunds()
+			}
+			b.ReportMetric(float64(entries*b.N)/b.Elapsed().Seconds(), "entries/s")
+		})
+	}
 }
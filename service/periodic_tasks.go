@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -8,85 +9,391 @@ import (
 	"time"
 
 	"github.com/planetmint/planetmint-go/util"
+	elementstypes "github.com/rddl-network/elements-rpc/types"
 	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func (r2p *R2PService) registerPeriodicTasks() {
-	r2p.tickerList = append(r2p.tickerList, time.NewTicker(2*time.Hour))
-	r2p.tickerList = append(r2p.tickerList, time.NewTicker(2*time.Minute))
+	cfg := config.GetConfig()
+	r2p.tickerList = append(r2p.tickerList, time.NewTicker(cfg.CleanupInterval))
+	r2p.tickerList = append(r2p.tickerList, time.NewTicker(cfg.ConversionInterval))
+	// the reconciliation is disabled with a reconciliation-interval of 0, its ticks never arrive then
+	var reconciliationTicks <-chan time.Time
+	if cfg.ReconciliationInterval > 0 {
+		ticker := time.NewTicker(cfg.ReconciliationInterval)
+		r2p.tickerList = append(r2p.tickerList, ticker)
+		reconciliationTicks = ticker.C
+	}
+	r2p.workers.Add(1)
 	go func() {
+		defer r2p.workers.Done()
 		for {
 			select {
+			case <-r2p.done:
+				return
 			case <-r2p.tickerList[0].C:
-				go r2p.cleanupDB()
+				r2p.startPass(r2p.cleanupPass)
 			case <-r2p.tickerList[1].C:
-				go r2p.convertArrivedFunds()
+				r2p.startPass(r2p.conversionPass)
+			case <-reconciliationTicks:
+				r2p.startPass(r2p.reconciliationPass)
 			}
 		}
 	}()
 }
 
-func (r2p *R2PService) ExecutePotentialConversion(conversion ConversionRequest) (deleteEntry bool, err error) {
+// ExecutePotentialConversion advances the conversion request through its lifecycle: it checks the receive
+// address for incoming deposits, mints the corresponding amount of PLMNT for every confirmed deposit and
+// confirms the mints on Planetmint. The outcome is persisted with the conversion request.
+func (r2p *R2PService) ExecutePotentialConversion(ctx context.Context, conversion ConversionRequest) (err error) {
+	return r2p.executeConversion(ctx, conversion, nil)
+}
+
+// executeConversion is ExecutePotentialConversion, except that deposits ready to be minted are queued in
+// the batch instead of being minted right away if a batch is given.
+func (r2p *R2PService) executeConversion(ctx context.Context, conversion ConversionRequest, batch *mintBatch) (err error) {
+	ctx, span := tracer.Start(ctx, "ExecutePotentialConversion", trace.WithAttributes(
+		attrLiquidAddress.String(conversion.ConfidentialAddress), attrBeneficiary.String(conversion.PlanetmintAddress)))
+	defer func() { endSpan(span, err) }()
+	ctx = withConversion(ctx, conversion)
+	defer func() {
+		conversion.LastError = ""
+		if err != nil {
+			conversion.LastError = err.Error()
+		}
+		if putErr := r2p.storeConversionRequest(&conversion); putErr != nil {
+			r2p.log(ctx).Error("msg", "storing conversion state failed", "error", putErr)
+		}
+	}()
+
+	conversion.initState()
+	err = r2p.detectFunds(ctx, &conversion)
+	if err != nil || conversion.State == types.StateNeedsReview {
+		return
+	}
+	err = conversion.updateState()
+	if err != nil {
+		return
+	}
+
+	var errs []error
+	for i := range conversion.Deposits {
+		deposit := &conversion.Deposits[i]
+		if deposit.State == types.StateConfirmed && batch != nil && !deposit.MintSeparately {
+			errs = append(errs, r2p.queueMint(ctx, &conversion, deposit, batch))
+			continue
+		}
+		if deposit.State == types.StateConfirmed {
+			errs = append(errs, r2p.mint(ctx, &conversion, deposit))
+			continue
+		}
+		if deposit.State == types.StateMintBroadcast {
+			errs = append(errs, r2p.confirmMint(ctx, deposit))
+		}
+	}
+	errs = append(errs, conversion.updateState())
+	err = errors.Join(errs...)
+	return
+}
+
+// detectFunds checks the receive address for deposits, registers new deposits and moves deposits with
+// enough confirmations to confirmed. The amount of every deposit is fetched individually from the wallet.
+// Deposits whose transaction pays further receive addresses are put under review, as Planetmint only
+// takes one mint request per Liquid transaction.
+func (r2p *R2PService) detectFunds(ctx context.Context, conversion *ConversionRequest) (err error) {
 	cfg := config.GetConfig()
-	txDetails, err := r2p.eClient.ListReceivedByAddress(cfg.GetElementsURL(),
-		[]string{strconv.Itoa(int(cfg.Confirmations)), "false", "true", `"` + conversion.ConfidentialAddress + `"`, `"` + cfg.AcceptedAsset + `"`})
+	listCtx, span := tracer.Start(ctx, "ListReceivedByAddress", trace.WithAttributes(attrLiquidAddress.String(conversion.ConfidentialAddress)))
+	txDetails, err := r2p.eClient.ListReceivedByAddress(listCtx, cfg.GetElementsURL(),
+		[]string{"0", "false", "true", `"` + conversion.ConfidentialAddress + `"`, `"` + cfg.AcceptedAsset + `"`})
+	endSpan(span, err)
 	if err != nil {
-		msg := "error: invalid call to rpc with address " + conversion.ConfidentialAddress + " : " + err.Error()
-		r2p.logger.Error("error", msg)
-		err = errors.New(msg)
+		r2p.log(ctx).Error("msg", "listing received transactions failed", "error", err)
+		err = errors.New("error: invalid call to rpc with address " + conversion.ConfidentialAddress + " : " + err.Error())
 		return
 	}
 	if len(txDetails) == 0 {
-		msg := "the address hasn't received any transactions for the given asset: %s - %s"
-		r2p.logger.Debug("msg", fmt.Sprintf(msg, conversion.ConfidentialAddress, cfg.AcceptedAsset))
+		r2p.log(ctx).Debug("msg", "no transactions received for the accepted asset", "asset", cfg.AcceptedAsset)
 		return
 	} else if len(txDetails) > 1 {
+		r2p.log(ctx).Error("msg", "unexpected tx details for the address", "details", len(txDetails))
 		msg := "the tx details for the address are unexpected: " + conversion.ConfidentialAddress
-		r2p.logger.Error("error", msg)
-		err = errors.New(msg)
+		err = errors.Join(errors.New(msg), conversion.Transition(types.StateNeedsReview))
 		return
 	}
-	if len(txDetails[0].TxIDs) > 1 {
-		// create error that there are too much transactions
-		msg := "error: the account received more than 1 transaction: " + conversion.ConfidentialAddress
-		r2p.logger.Error("error", msg)
-		err = errors.New(msg)
+
+	var total uint64
+	for _, txID := range txDetails[0].TxIDs {
+		deposit := conversion.deposit(txID)
+		if deposit != nil && deposit.State != types.StateFundsDetected {
+			total += deposit.RDDLAmount
+			continue
+		}
+
+		tx, err := r2p.eClient.GetTransaction(ctx, cfg.GetElementsURL(), []string{`"` + txID + `"`})
+		if err != nil {
+			r2p.log(ctx).Error("msg", "fetching transaction failed", "txid", txID, "error", err)
+			return errors.New("error: fetching tx " + txID + " received by " + conversion.ConfidentialAddress + " : " + err.Error())
+		}
+		if deposit == nil {
+			r2p.log(ctx).Info("msg", "deposit detected", "txid", txID)
+			conversion.Deposits = append(conversion.Deposits, types.Deposit{LiquidTxID: txID, State: types.StateFundsDetected})
+			deposit = &conversion.Deposits[len(conversion.Deposits)-1]
+			depositsDetected.Inc()
+		}
+		deposit.RDDLAmount = receivedAmount(tx, conversion.ConfidentialAddress)
+		deposit.Confirmations = uint64(max(tx.Confirmations, 0))
+		total += deposit.RDDLAmount
+
+		if deposit.Confirmations < uint64(cfg.Confirmations) {
+			r2p.log(ctx).Debug("msg", "waiting for confirmations", "txid", txID, "confirmations", deposit.Confirmations, "required", cfg.Confirmations)
+			continue
+		}
+		err = transitionDeposit(deposit, types.StateConfirmed)
+		if err != nil {
+			return err
+		}
+		rddlReceived.WithLabelValues(cfg.AcceptedAsset).Add(float64(deposit.RDDLAmount) / float64(util.Factor))
+		if paysOtherAddresses(tx, conversion.ConfidentialAddress) && !deposit.Approved {
+			r2p.log(ctx).Info("msg", "deposit pays further receive addresses and needs to be reviewed", "txid", txID)
+			err = transitionDeposit(deposit, types.StateNeedsReview)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// the deposits are minted individually, so they have to account for everything the address received.
+	// Deposits approved by an operator are minted with their recorded amount.
+	if received := util.RDDLToken2Uint(txDetails[0].Amount); total != received {
+		reviewed, err := conversion.reviewPendingDeposits()
+		if err != nil {
+			return err
+		}
+		if reviewed > 0 {
+			r2p.log(ctx).Error("msg", "deposits do not add up to the received amount", "deposits", total, "received", received)
+			msg := fmt.Sprintf("the deposits of %s add up to %d instead of the received %d", conversion.ConfidentialAddress, total, received)
+			return errors.Join(errors.New(msg), conversion.updateState())
+		}
+		r2p.log(ctx).Warn("msg", "deposits do not add up to the received amount", "deposits", total, "received", received)
+	}
+	return conversion.updateState()
+}
+
+// receivedAmount returns the RDDL the transaction paid to the address. A transaction may pay several
+// receive addresses, e.g. a batched payout, so the amount of the whole transaction does not apply.
+func receivedAmount(tx elementstypes.GetTransactionResult, address string) (amount uint64) {
+	for _, detail := range tx.Details {
+		if detail.Category == categoryReceive && detail.Address == address {
+			amount += util.RDDLToken2Uint(detail.Amount)
+		}
+	}
+	return
+}
+
+// paysOtherAddresses reports whether the transaction pays further addresses of the wallet.
+func paysOtherAddresses(tx elementstypes.GetTransactionResult, address string) bool {
+	for _, detail := range tx.Details {
+		if detail.Category == categoryReceive && detail.Address != address {
+			return true
+		}
+	}
+	return false
+}
+
+// mint issues the mint request for a confirmed deposit.
+func (r2p *R2PService) mint(ctx context.Context, conversion *ConversionRequest, deposit *types.Deposit) (err error) {
+	// a transaction paying several receive addresses must not be minted by two workers at once
+	unlock := r2p.txLocks.lock(deposit.LiquidTxID)
+	defer unlock()
+
+	ready, err := r2p.prepareMint(ctx, conversion, deposit)
+	if err != nil || !ready {
 		return
 	}
-	r2p.logger.Info("msg", "Conversion: "+conversion.ConfidentialAddress+" received tx: "+txDetails[0].TxIDs[0])
-	liquidTxHash := txDetails[0].TxIDs[0]
+	return r2p.mintDeposit(ctx, conversion.PlanetmintAddress, deposit)
+}
+
+// mintDeposit broadcasts the mint of a deposit whose amount is fixed and records the outcome with it.
+func (r2p *R2PService) mintDeposit(ctx context.Context, beneficiary string, deposit *types.Deposit) (err error) {
+	ctx, span := tracer.Start(ctx, "MintPLMNT", trace.WithAttributes(attrLiquidTxID.String(deposit.LiquidTxID),
+		attrBeneficiary.String(beneficiary), attrPLMNTAmount.Int64(int64(deposit.PLMNTAmount))))
+	defer func() { endSpan(span, err) }()
+
+	result, err := r2p.pmClient.MintPLMNT(ctx, beneficiary, deposit.PLMNTAmount, deposit.LiquidTxID)
+	span.SetAttributes(attrPlanetmintTxHash.String(result.TxHash))
+	return r2p.applyMintResult(ctx, beneficiary, deposit, result, err)
+}
 
+// prepareMint checks whether a confirmed deposit is ready to be minted and fixes the amount to mint. The
+// PLMNT fraction cut away by the conversion is kept in the beneficiary's remainder ledger and credited on
+// the next conversion. Deposits too small to mint a whole PLMNT are credited to the ledger instead of
+// being minted. The caller holds the lock of the deposit's Liquid transaction.
+func (r2p *R2PService) prepareMint(ctx context.Context, conversion *ConversionRequest, deposit *types.Deposit) (ready bool, err error) {
 	// check if mint request has already been issued
-	code, err := r2p.checkMintRequest(liquidTxHash)
+	code, err := r2p.checkMintRequest(ctx, deposit.LiquidTxID)
 	if err != nil {
-		msg := "error while checking mint request: " + err.Error() + " code: " + strconv.Itoa(code) + " for address " + conversion.ConfidentialAddress
-		r2p.logger.Error("error", msg)
-		err = errors.New(msg)
+		r2p.log(ctx).Error("msg", "checking mint request failed", "txid", deposit.LiquidTxID, "code", code, "error", err)
+		err = errors.New("error while checking mint request: " + err.Error() + " code: " + strconv.Itoa(code) + " for tx " + deposit.LiquidTxID)
 		return
 	} else if code == http.StatusConflict {
-		deleteEntry = true
-		msg := "tx " + liquidTxHash + " got already minted"
-		r2p.logger.Debug("msg", msg)
-		return
+		r2p.log(ctx).Debug("msg", "deposit got already minted", "txid", deposit.LiquidTxID)
+		return false, transitionDeposit(deposit, types.StateMintConfirmed)
+	}
+
+	// deposits outside the configured limits are not minted automatically
+	cfg := config.GetConfig()
+	if deposit.RDDLAmount < util.RDDLToken2Uint(cfg.MinDeposit) {
+		r2p.log(ctx).Info("msg", "deposit is below the minimum deposit and is flagged as dust", "txid", deposit.LiquidTxID)
+		return false, transitionDeposit(deposit, types.StateDust)
+	}
+	if cfg.MaxDeposit > 0 && deposit.RDDLAmount > util.RDDLToken2Uint(cfg.MaxDeposit) && !deposit.Approved {
+		r2p.log(ctx).Info("msg", "deposit exceeds the maximum deposit and needs to be approved", "txid", deposit.LiquidTxID)
+		return false, transitionDeposit(deposit, types.StateNeedsReview)
+	}
+
+	// the rate is fixed with the first mint attempt so that retries mint the same amount
+	if deposit.ConversionRate == 0 {
+		rate, err := r2p.rateProvider.GetRate()
+		if err != nil {
+			r2p.log(ctx).Error("msg", "fetching conversion rate failed", "txid", deposit.LiquidTxID, "error", err)
+			return false, errors.New("error while fetching conversion rate for tx " + deposit.LiquidTxID + ": " + err.Error())
+		}
+		deposit.ConversionRate = rate.Rate
+	}
+
+	// the amount is fixed and its remainder stored with the first mint attempt as well
+	if deposit.PLMNTAmount != 0 {
+		return true, nil
+	}
+
+	// the beneficiary's ledger must not change between reading the credit and storing the new remainder
+	beneficiary := conversion.PlanetmintAddress
+	unlockLedger := r2p.ledgerLocks.lock(beneficiary)
+	defer unlockLedger()
+	credit, err := r2p.getRemainder(beneficiary)
+	if err != nil {
+		r2p.log(ctx).Error("msg", "reading remainder failed", "txid", deposit.LiquidTxID, "error", err)
+		return false, errors.New("error while reading remainder of " + beneficiary + ": " + err.Error())
+	}
+	plmntAmount, remainder := GetConversionWithRemainder(deposit.RDDLAmount, deposit.ConversionRate, credit)
+	unprepared := *deposit
+	deposit.PLMNTAmount, deposit.Remainder, deposit.RemainderCredit = plmntAmount, remainder, credit
+	if deposit.PLMNTAmount == 0 {
+		r2p.log(ctx).Info("msg", "deposit is too small to be minted and is credited to the beneficiary", "txid", deposit.LiquidTxID)
+		if err = transitionDeposit(deposit, types.StateCredited); err != nil {
+			*deposit = unprepared
+			return
+		}
+	}
+
+	// the remainder is stored together with the fixed amount, so that neither is lost without the other
+	batch := new(leveldb.Batch)
+	setRemainder(batch, beneficiary, remainder)
+	err = r2p.putConversionRequest(*conversion, batch)
+	if err != nil {
+		*deposit = unprepared
+		r2p.log(ctx).Error("msg", "storing remainder failed", "txid", deposit.LiquidTxID, "error", err)
+		return false, errors.New("error while storing remainder of tx " + deposit.LiquidTxID + " for " + beneficiary + ": " + err.Error())
 	}
+	return deposit.PLMNTAmount != 0, nil
+}
 
-	convertedAmount := util.RDDLToken2Uint(txDetails[0].Amount)
-	plmntAmount := GetConversion(convertedAmount)
-	err = r2p.pmClient.MintPLMNT(conversion.PlanetmintAddress, plmntAmount, liquidTxHash)
+// applyMintResult records the outcome of a mint broadcast with the deposit. A failed or rejected broadcast
+// is retried by the next conversion pass, after maxMintAttempts failed attempts the deposit is marked as
+// failed instead.
+func (r2p *R2PService) applyMintResult(ctx context.Context, beneficiary string, deposit *types.Deposit, result types.TxResult, err error) error {
+	deposit.MintAttempts++
+	if err != nil {
+		mintFailures.WithLabelValues(mintFailureError).Inc()
+	} else {
+		deposit.PlanetmintTxHash = result.TxHash
+		deposit.PlanetmintTxCode = result.Code
+		if result.Code != 0 {
+			mintFailures.WithLabelValues(mintFailureRejected).Inc()
+			err = fmt.Errorf("mint tx %s rejected with code %d: %s", result.TxHash, result.Code, result.Log)
+		}
+	}
 	if err != nil {
-		msg := "error while minting " + strconv.FormatUint(plmntAmount, 10) + " tokens (tx id " + liquidTxHash + ") for address " + conversion.PlanetmintAddress
-		r2p.logger.Error("msg", msg)
-		err = errors.New(msg)
+		r2p.log(ctx).Error("msg", "minting failed", "txid", deposit.LiquidTxID, "amount", deposit.PLMNTAmount, "attempt", deposit.MintAttempts, "error", err)
+		err = errors.New("error while minting " + strconv.FormatUint(deposit.PLMNTAmount, 10) + " tokens (tx id " + deposit.LiquidTxID + ") for address " + beneficiary + ": " + err.Error())
+		if deposit.MintAttempts >= maxMintAttempts {
+			err = errors.Join(err, transitionDeposit(deposit, types.StateFailed))
+		}
+		return err
 	}
+	if result.Simulated {
+		r2p.log(ctx).Info("msg", "mint simulated", "txid", deposit.LiquidTxID, "amount", deposit.PLMNTAmount, "gas-used", result.GasUsed)
+		deposit.SimulatedGas = result.GasUsed
+		return transitionDeposit(deposit, types.StateSimulated)
+	}
+	r2p.log(ctx).Info("msg", "mint broadcast", "txid", deposit.LiquidTxID, "amount", deposit.PLMNTAmount, "planetmint-txhash", result.TxHash)
+	deposit.BroadcastAt = time.Now().Unix()
+	return transitionDeposit(deposit, types.StateMintBroadcast)
+}
 
+// confirmMint moves a broadcast deposit to mint-confirmed once the mint request exists on Planetmint. If
+// the mint transaction failed in its block or did not make it into a block within mint-inclusion-timeout,
+// the deposit is moved back to confirmed to be minted again. Deposits whose mint transaction failed in its
+// block are minted in a transaction of their own from then on.
+func (r2p *R2PService) confirmMint(ctx context.Context, deposit *types.Deposit) (err error) {
+	code, err := r2p.checkMintRequest(ctx, deposit.LiquidTxID)
+	if err != nil {
+		r2p.log(ctx).Error("msg", "checking mint request failed", "txid", deposit.LiquidTxID, "code", code, "error", err)
+		err = errors.New("error while checking mint request: " + err.Error() + " code: " + strconv.Itoa(code) + " for tx " + deposit.LiquidTxID)
+		return
+	}
+	if code == http.StatusConflict {
+		r2p.log(ctx).Info("msg", "mint confirmed", "txid", deposit.LiquidTxID, "amount", deposit.PLMNTAmount)
+		return transitionDeposit(deposit, types.StateMintConfirmed)
+	}
+	r2p.log(ctx).Debug("msg", "mint request not yet found on planetmint", "txid", deposit.LiquidTxID)
+
+	// deposits broadcast before the broadcast result was tracked can only wait for the mint request
+	if deposit.PlanetmintTxHash == "" {
+		return
+	}
+	result, included, err := r2p.pmClient.GetTxResult(ctx, deposit.PlanetmintTxHash)
+	if err != nil {
+		r2p.log(ctx).Error("msg", "fetching planetmint tx failed", "txid", deposit.LiquidTxID, "planetmint-txhash", deposit.PlanetmintTxHash, "error", err)
+		return errors.New("error while fetching planetmint tx " + deposit.PlanetmintTxHash + " for tx " + deposit.LiquidTxID + ": " + err.Error())
+	}
+	if included && result.Code != 0 {
+		// a batched mint fails as a whole, so a single bad mint request must not hold back the others again
+		deposit.PlanetmintTxCode = result.Code
+		deposit.MintSeparately = true
+		mintFailures.WithLabelValues(mintFailureFailed).Inc()
+		return r2p.retryMint(ctx, deposit, fmt.Sprintf("mint tx %s failed with code %d: %s", result.TxHash, result.Code, result.Log))
+	}
+	timeout := config.GetConfig().MintInclusionTimeout
+	if time.Since(time.Unix(deposit.BroadcastAt, 0)) > timeout {
+		mintFailures.WithLabelValues(mintFailureNotIncluded).Inc()
+		return r2p.retryMint(ctx, deposit, "mint request of tx "+deposit.PlanetmintTxHash+" not found on planetmint within "+timeout.String())
+	}
 	return
 }
 
-func (r2p *R2PService) checkMintRequest(liquidTxHash string) (code int, err error) {
+// retryMint moves a deposit whose mint did not succeed back to confirmed, or to failed once maxMintAttempts
+// is reached.
+func (r2p *R2PService) retryMint(ctx context.Context, deposit *types.Deposit, reason string) (err error) {
+	r2p.log(ctx).Error("msg", "mint did not succeed", "txid", deposit.LiquidTxID, "attempt", deposit.MintAttempts, "reason", reason)
+	err = errors.New("error while minting tx " + deposit.LiquidTxID + ": " + reason)
+	if deposit.MintAttempts >= maxMintAttempts {
+		return errors.Join(err, transitionDeposit(deposit, types.StateFailed))
+	}
+	return errors.Join(err, transitionDeposit(deposit, types.StateConfirmed))
+}
+
+func (r2p *R2PService) checkMintRequest(ctx context.Context, liquidTxHash string) (code int, err error) {
+	ctx, span := tracer.Start(ctx, "CheckMintRequest", trace.WithAttributes(attrLiquidTxID.String(liquidTxHash)))
+	defer func() { endSpan(span, err) }()
+
 	// check whether mint request already exists
-	mr, err := r2p.pmClient.CheckMintRequest(liquidTxHash)
+	mr, err := r2p.pmClient.CheckMintRequest(ctx, liquidTxHash)
 	if err != nil {
-		r2p.logger.Error("msg", "error while fetching mint request: "+err.Error())
+		r2p.log(ctx).Error("msg", "fetching mint request failed", "txid", liquidTxHash, "error", err)
 		code = http.StatusInternalServerError
 		err = fmt.Errorf("error while fetching mint request: %w", err)
 		return
@@ -94,7 +401,7 @@ func (r2p *R2PService) checkMintRequest(liquidTxHash string) (code int, err erro
 
 	// return because mint request for txhash is already
 	if mr != nil {
-		r2p.logger.Debug("msg", "mint request: txid "+liquidTxHash+" got minted before ("+mr.String()+")")
+		r2p.log(ctx).Debug("msg", "mint request exists", "txid", liquidTxHash, "mint-request", mr.String())
 		code = http.StatusConflict
 		return
 	}
@@ -2,63 +2,268 @@ package service
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	clienttx "github.com/cosmos/cosmos-sdk/client/tx"
 	"github.com/cosmos/cosmos-sdk/codec"
-	types "github.com/cosmos/cosmos-sdk/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/std"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
 
 	"github.com/planetmint/planetmint-go/lib"
 	daotypes "github.com/planetmint/planetmint-go/x/dao/types"
 	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 )
 
 type IPlanetmintClient interface {
-	MintPLMNT(beneficiary string, amount uint64, liquidTxHash string) (err error)
-	CheckMintRequest(txhash string) (mintRequest *daotypes.QueryGetMintRequestsByHashResponse, err error)
+	MintPLMNT(ctx context.Context, beneficiary string, amount uint64, liquidTxHash string) (result types.TxResult, err error)
+	MintPLMNTBatch(ctx context.Context, mintRequests []daotypes.MintRequest) (result types.TxResult, err error)
+	CheckMintRequest(ctx context.Context, txhash string) (mintRequest *daotypes.QueryGetMintRequestsByHashResponse, err error)
+	GetTxResult(ctx context.Context, txHash string) (result types.TxResult, included bool, err error)
+	Health(ctx context.Context) (err error)
+	GetChainID(ctx context.Context) (chainID string, err error)
+	GetMintAddress(ctx context.Context) (mintAddress string, err error)
+	CheckSigningKey(address string) (err error)
 }
 
-type PlanetmintClient struct{}
-
-func NewPlanetmintClient() *PlanetmintClient {
-	return &PlanetmintClient{}
+// PlanetmintClient queries Planetmint over a single long-lived gRPC connection. The connection is kept
+// alive with keepalive pings and is re-established with exponential backoff if it breaks.
+type PlanetmintClient struct {
+	conn *grpc.ClientConn
+	// broadcastMu keeps the gas of the library config in place until the transaction is broadcast
+	broadcastMu sync.Mutex
+	// registry and txConfig decode accounts and encode transactions for simulations
+	registry codectypes.InterfaceRegistry
+	txConfig client.TxConfig
 }
 
-func (pmc *PlanetmintClient) MintPLMNT(beneficiary string, amount uint64, liquidTxHash string) (err error) {
+func NewPlanetmintClient() (pmc *PlanetmintClient, err error) {
 	cfg := config.GetConfig()
+	creds, err := transportCredentials(cfg)
+	if err != nil {
+		return
+	}
+	conn, err := grpc.Dial(
+		cfg.PlanetmintRPCHost,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec.NewProtoCodec(nil).GRPCCodec())),
+		grpc.WithChainUnaryInterceptor(observePlanetmintRPC),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                30 * time.Second,
+			Timeout:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: 10 * time.Second,
+		}),
+	)
+	if err != nil {
+		return
+	}
+
+	registry := codectypes.NewInterfaceRegistry()
+	std.RegisterInterfaces(registry)
+	authtypes.RegisterInterfaces(registry)
+	daotypes.RegisterInterfaces(registry)
+	txConfig := authtx.NewTxConfig(codec.NewProtoCodec(registry), authtx.DefaultSignModes)
+	return &PlanetmintClient{conn: conn, registry: registry, txConfig: txConfig}, nil
+}
+
+// transportCredentials returns TLS credentials if planetmint-tls is enabled, verified against the
+// planetmint-tls-ca certificate or the system roots if no CA is configured.
+func transportCredentials(cfg *config.Config) (creds credentials.TransportCredentials, err error) {
+	if !cfg.PlanetmintTLS {
+		return insecure.NewCredentials(), nil
+	}
+	if cfg.PlanetmintTLSCA == "" {
+		return credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12}), nil
+	}
+	creds, err = credentials.NewClientTLSFromFile(cfg.PlanetmintTLSCA, "")
+	if err != nil {
+		err = fmt.Errorf("loading planetmint-tls-ca: %w", err)
+	}
+	return
+}
+
+// Close closes the connection to Planetmint.
+func (pmc *PlanetmintClient) Close() (err error) {
+	return pmc.conn.Close()
+}
+
+// Health waits until the connection to Planetmint is ready or the context is done.
+func (pmc *PlanetmintClient) Health(ctx context.Context) (err error) {
+	for {
+		state := pmc.conn.GetState()
+		if state == connectivity.Ready {
+			return
+		}
+		if state == connectivity.Shutdown {
+			return errors.New("planetmint connection is closed")
+		}
+		if state == connectivity.Idle {
+			pmc.conn.Connect()
+		}
+		if !pmc.conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("planetmint connection is %s: %w", state, ctx.Err())
+		}
+	}
+}
+
+// MintPLMNT broadcasts the mint request and returns the hash and CheckTx code of the transaction. The
+// broadcast itself cannot be cancelled, so the context is only checked before broadcasting.
+func (pmc *PlanetmintClient) MintPLMNT(ctx context.Context, beneficiary string, amount uint64, liquidTxHash string) (result types.TxResult, err error) {
 	mintRequest := daotypes.MintRequest{
 		Beneficiary:  beneficiary,
 		Amount:       amount,
 		LiquidTxHash: liquidTxHash,
 	}
+	return pmc.MintPLMNTBatch(ctx, []daotypes.MintRequest{mintRequest})
+}
+
+// MintPLMNTBatch broadcasts the mint requests in a single transaction, so they succeed or fail together.
+// The transaction gets planetmint-tx-gas for every mint request. In dry-run mode the transaction is
+// simulated instead of being broadcast.
+func (pmc *PlanetmintClient) MintPLMNTBatch(ctx context.Context, mintRequests []daotypes.MintRequest) (result types.TxResult, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	cfg := config.GetConfig()
+	addr := sdk.MustAccAddressFromBech32(cfg.PlanetmintAddress)
+	msgs := make([]sdk.Msg, 0, len(mintRequests))
+	for i := range mintRequests {
+		msgs = append(msgs, daotypes.NewMsgMintToken(cfg.PlanetmintAddress, &mintRequests[i]))
+	}
+	if cfg.DryRun {
+		return pmc.simulate(ctx, addr, msgs...)
+	}
+
+	pmc.broadcastMu.Lock()
+	defer pmc.broadcastMu.Unlock()
+	lib.GetConfig().SetTxGas(txGas(len(msgs)))
+	started := time.Now()
+	out, err := lib.BroadcastTxWithFileLock(addr, msgs...)
+	observeRPC("planetmint", "broadcast", started, err)
+	if err != nil {
+		return
+	}
+	txResponse, err := lib.GetTxResponseFromOut(out)
+	if err != nil {
+		err = fmt.Errorf("parsing broadcast response: %w", err)
+		return
+	}
+	result = types.TxResult{TxHash: txResponse.TxHash, Code: txResponse.Code, Log: txResponse.RawLog}
+	return
+}
+
+// simulate runs the transaction through the ante handlers and message handlers of Planetmint without
+// broadcasting it. The simulation fails like the broadcast would, e.g. if planetmint-address is not the
+// chain's MintAddress, and reports the gas the transaction uses. Signatures are not checked, so the
+// signing key does not need to be in the keyring.
+func (pmc *PlanetmintClient) simulate(ctx context.Context, addr sdk.AccAddress, msgs ...sdk.Msg) (result types.TxResult, err error) {
+	cfg := config.GetConfig()
+	ctx, cancel := context.WithTimeout(ctx, cfg.RPCTimeout)
+	defer cancel()
+	// the account is packed into an Any, so the response is decoded with a registry that knows accounts
+	codecOption := grpc.ForceCodec(codec.NewProtoCodec(pmc.registry).GRPCCodec())
 
-	addr := types.MustAccAddressFromBech32(cfg.PlanetmintAddress)
-	msg := daotypes.NewMsgMintToken(cfg.PlanetmintAddress, &mintRequest)
+	authClient := authtypes.NewQueryClient(pmc.conn)
+	res, err := authClient.Account(ctx, &authtypes.QueryAccountRequest{Address: addr.String()}, codecOption)
+	if err != nil {
+		err = fmt.Errorf("fetching account %s: %w", addr, err)
+		return
+	}
+	account, ok := res.Account.GetCachedValue().(authtypes.AccountI)
+	if !ok {
+		err = fmt.Errorf("unexpected account type %s", res.Account.TypeUrl)
+		return
+	}
 
-	_, err = lib.BroadcastTxWithFileLock(addr, msg)
+	txf := clienttx.Factory{}.
+		WithChainID(cfg.PlanetmintChainID).
+		WithTxConfig(pmc.txConfig).
+		WithAccountNumber(account.GetAccountNumber()).
+		WithSequence(account.GetSequence()).
+		WithGas(txGas(len(msgs)))
+	txBytes, err := txf.BuildSimTx(msgs...)
+	if err != nil {
+		return
+	}
+	txClient := txtypes.NewServiceClient(pmc.conn)
+	simRes, err := txClient.Simulate(ctx, &txtypes.SimulateRequest{TxBytes: txBytes}, codecOption)
 	if err != nil {
 		return
 	}
 
+	result = types.TxResult{Simulated: true}
+	if simRes.GasInfo != nil {
+		result.GasUsed = simRes.GasInfo.GasUsed
+	}
+	if simRes.Result != nil {
+		result.Log = simRes.Result.Log
+	}
+	if gas := txGas(len(msgs)); result.GasUsed > gas {
+		err = fmt.Errorf("the mint uses %d gas, more than the %d gas of planetmint-tx-gas for %d mint requests", result.GasUsed, gas, len(msgs))
+	}
 	return
 }
 
-func (pmc *PlanetmintClient) CheckMintRequest(txhash string) (mintRequest *daotypes.QueryGetMintRequestsByHashResponse, err error) {
+// txGas returns the gas of a transaction with the given number of mint requests.
+func txGas(mintRequests int) uint64 {
+	return config.GetConfig().PlanetmintTxGas * uint64(mintRequests)
+}
+
+// GetTxResult looks up the transaction on Planetmint. included is false if the transaction is not part of
+// a block (yet).
+func (pmc *PlanetmintClient) GetTxResult(ctx context.Context, txHash string) (result types.TxResult, included bool, err error) {
 	cfg := config.GetConfig()
-	grcpConn, err := grpc.Dial(
-		cfg.PlanetmintRPCHost,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec.NewProtoCodec(nil).GRPCCodec())),
-	)
+	ctx, cancel := context.WithTimeout(ctx, cfg.RPCTimeout)
+	defer cancel()
+	txClient := txtypes.NewServiceClient(pmc.conn)
+	res, err := txClient.GetTx(ctx, &txtypes.GetTxRequest{Hash: txHash})
 	if err != nil {
+		if strings.Contains(err.Error(), codes.NotFound.String()) {
+			err = nil
+		}
 		return
 	}
 
-	daoClient := daotypes.NewQueryClient(grcpConn)
+	included = true
+	result = types.TxResult{TxHash: txHash}
+	if res.TxResponse != nil {
+		result.Code = res.TxResponse.Code
+		result.Log = res.TxResponse.RawLog
+	}
+	return
+}
+
+func (pmc *PlanetmintClient) CheckMintRequest(ctx context.Context, txhash string) (mintRequest *daotypes.QueryGetMintRequestsByHashResponse, err error) {
+	cfg := config.GetConfig()
+	ctx, cancel := context.WithTimeout(ctx, cfg.RPCTimeout)
+	defer cancel()
+	daoClient := daotypes.NewQueryClient(pmc.conn)
 	mintRequest, err = daoClient.GetMintRequestsByHash(
-		context.Background(),
+		ctx,
 		&daotypes.QueryGetMintRequestsByHashRequest{Hash: txhash},
 	)
 
@@ -72,10 +277,50 @@ func (pmc *PlanetmintClient) CheckMintRequest(txhash string) (mintRequest *daoty
 	return
 }
 
+// GetChainID returns the chain id of the Planetmint node.
+func (pmc *PlanetmintClient) GetChainID(ctx context.Context) (chainID string, err error) {
+	cfg := config.GetConfig()
+	ctx, cancel := context.WithTimeout(ctx, cfg.RPCTimeout)
+	defer cancel()
+	nodeClient := tmservice.NewServiceClient(pmc.conn)
+	res, err := nodeClient.GetNodeInfo(ctx, &tmservice.GetNodeInfoRequest{})
+	if err != nil {
+		return
+	}
+	return res.GetDefaultNodeInfo().GetNetwork(), nil
+}
+
+// GetMintAddress returns the address that is allowed to mint PLMNT on Planetmint.
+func (pmc *PlanetmintClient) GetMintAddress(ctx context.Context) (mintAddress string, err error) {
+	cfg := config.GetConfig()
+	ctx, cancel := context.WithTimeout(ctx, cfg.RPCTimeout)
+	defer cancel()
+	daoClient := daotypes.NewQueryClient(pmc.conn)
+	res, err := daoClient.Params(ctx, &daotypes.QueryParamsRequest{})
+	if err != nil {
+		return
+	}
+	return res.Params.MintAddress, nil
+}
+
+// CheckSigningKey checks that the keyring used to sign Planetmint transactions holds the key of the address.
+func (pmc *PlanetmintClient) CheckSigningKey(address string) (err error) {
+	addr, err := sdk.AccAddressFromBech32(address)
+	if err != nil {
+		return
+	}
+	keyring, err := lib.GetConfig().GetLibKeyring()
+	if err != nil {
+		return
+	}
+	_, err = keyring.KeyByAddress(addr)
+	return
+}
+
 // verifyAddress verifies the integrity and prefix of a given address.
 func VerifyAddress(address string) (valid bool, err error) {
 	// Attempt to decode the address
-	_, err = types.AccAddressFromBech32(address)
+	_, err = sdk.AccAddressFromBech32(address)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,199 @@
+package service_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/std"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	daotypes "github.com/planetmint/planetmint-go/x/dao/types"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/service"
+	"github.com/rddl-network/rddl-2-plmnt-service/testutil"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPlanetmintClientHealth(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := grpc.NewServer()
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	cfg := config.GetConfig()
+	cfg.PlanetmintRPCHost = listener.Addr().String()
+	defer func() { cfg.PlanetmintRPCHost = config.DefaultConfig().PlanetmintRPCHost }()
+
+	pmClient, err := service.NewPlanetmintClient()
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, pmClient.Health(ctx))
+
+	// the connection is reused, a closed client is reported unhealthy
+	assert.NoError(t, pmClient.Close())
+	assert.Error(t, pmClient.Health(ctx))
+}
+
+// txServer answers GetTx for the transactions it knows and simulates transactions, like a Planetmint node.
+type txServer struct {
+	txtypes.UnimplementedServiceServer
+	txs       map[string]*sdk.TxResponse
+	txDecoder sdk.TxDecoder
+	simulated []sdk.Tx
+	gasUsed   uint64
+}
+
+func (s *txServer) Simulate(_ context.Context, req *txtypes.SimulateRequest) (*txtypes.SimulateResponse, error) {
+	tx, err := s.txDecoder(req.TxBytes)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	s.simulated = append(s.simulated, tx)
+	return &txtypes.SimulateResponse{GasInfo: &sdk.GasInfo{GasUsed: s.gasUsed}, Result: &sdk.Result{Log: "simulated"}}, nil
+}
+
+// authServer serves the account of planetmint-address.
+type authServer struct {
+	authtypes.UnimplementedQueryServer
+	account *codectypes.Any
+}
+
+func (s *authServer) Account(_ context.Context, _ *authtypes.QueryAccountRequest) (*authtypes.QueryAccountResponse, error) {
+	return &authtypes.QueryAccountResponse{Account: s.account}, nil
+}
+
+func (s *txServer) GetTx(_ context.Context, req *txtypes.GetTxRequest) (*txtypes.GetTxResponse, error) {
+	txResponse, ok := s.txs[req.Hash]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "tx not found: %s", req.Hash)
+	}
+	return &txtypes.GetTxResponse{TxResponse: txResponse}, nil
+}
+
+func TestPlanetmintClientGetTxResult(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := grpc.NewServer(grpc.ForceServerCodec(codec.NewProtoCodec(nil).GRPCCodec()))
+	txtypes.RegisterServiceServer(server, &txServer{txs: map[string]*sdk.TxResponse{
+		"FAILED": {TxHash: "FAILED", Code: 11, RawLog: "out of gas"},
+	}})
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	cfg := config.GetConfig()
+	cfg.PlanetmintRPCHost = listener.Addr().String()
+	defer func() { cfg.PlanetmintRPCHost = config.DefaultConfig().PlanetmintRPCHost }()
+
+	pmClient, err := service.NewPlanetmintClient()
+	assert.NoError(t, err)
+	defer pmClient.Close()
+
+	result, included, err := pmClient.GetTxResult(context.Background(), "FAILED")
+	assert.NoError(t, err)
+	assert.True(t, included)
+	assert.Equal(t, types.TxResult{TxHash: "FAILED", Code: 11, Log: "out of gas"}, result)
+
+	_, included, err = pmClient.GetTxResult(context.Background(), "PENDING")
+	assert.NoError(t, err)
+	assert.False(t, included)
+}
+
+func TestPlanetmintClientDryRun(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.DryRun = true
+	defer func() { cfg.DryRun = false }()
+
+	registry := codectypes.NewInterfaceRegistry()
+	std.RegisterInterfaces(registry)
+	authtypes.RegisterInterfaces(registry)
+	daotypes.RegisterInterfaces(registry)
+	txConfig := authtx.NewTxConfig(codec.NewProtoCodec(registry), authtx.DefaultSignModes)
+	account, err := codectypes.NewAnyWithValue(&authtypes.BaseAccount{Address: cfg.PlanetmintAddress, AccountNumber: 7, Sequence: 3})
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := grpc.NewServer(grpc.ForceServerCodec(codec.NewProtoCodec(registry).GRPCCodec()))
+	txService := &txServer{txDecoder: txConfig.TxDecoder(), gasUsed: 85000}
+	txtypes.RegisterServiceServer(server, txService)
+	authtypes.RegisterQueryServer(server, &authServer{account: account})
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	cfg.PlanetmintRPCHost = listener.Addr().String()
+	defer func() { cfg.PlanetmintRPCHost = config.DefaultConfig().PlanetmintRPCHost }()
+
+	pmClient, err := service.NewPlanetmintClient()
+	assert.NoError(t, err)
+	defer pmClient.Close()
+
+	// the mint is simulated with the account's sequence instead of being broadcast
+	result, err := pmClient.MintPLMNT(context.Background(), testutil.PlanetmintAddress, 200, "liquidtx")
+	assert.NoError(t, err)
+	assert.Equal(t, types.TxResult{Log: "simulated", Simulated: true, GasUsed: 85000}, result)
+	assert.Len(t, txService.simulated, 1)
+	sigTx, ok := txService.simulated[0].(authsigning.SigVerifiableTx)
+	assert.True(t, ok)
+	sigs, err := sigTx.GetSignaturesV2()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), sigs[0].Sequence)
+	msg, ok := sigTx.GetMsgs()[0].(*daotypes.MsgMintToken)
+	assert.True(t, ok)
+	assert.Equal(t, cfg.PlanetmintAddress, msg.Creator)
+	assert.Equal(t, uint64(200), msg.MintRequest.Amount)
+
+	// a mint that would run out of gas fails the simulation
+	txService.gasUsed = cfg.PlanetmintTxGas + 1
+	_, err = pmClient.MintPLMNT(context.Background(), testutil.PlanetmintAddress, 200, "liquidtx")
+	assert.ErrorContains(t, err, "planetmint-tx-gas")
+}
+
+func TestPlanetmintClientUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	cfg := config.GetConfig()
+	cfg.PlanetmintRPCHost = address
+	defer func() { cfg.PlanetmintRPCHost = config.DefaultConfig().PlanetmintRPCHost }()
+
+	pmClient, err := service.NewPlanetmintClient()
+	assert.NoError(t, err)
+	defer pmClient.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pmClient.Health(ctx), context.DeadlineExceeded)
+}
+
+func TestPlanetmintClientTLS(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.PlanetmintTLS = true
+	defer func() {
+		cfg.PlanetmintTLS = false
+		cfg.PlanetmintTLSCA = ""
+	}()
+
+	pmClient, err := service.NewPlanetmintClient()
+	assert.NoError(t, err)
+	assert.NoError(t, pmClient.Close())
+
+	cfg.PlanetmintTLSCA = "./missing-ca.pem"
+	_, err = service.NewPlanetmintClient()
+	assert.Error(t, err)
+}
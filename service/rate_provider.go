@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+)
+
+// RateProvider provides the number of PLMNT minted per RDDL.
+type RateProvider interface {
+	GetRate() (rate types.ConversionRate, err error)
+}
+
+// NewRateProvider returns the rate provider configured by rate-source: the static conversion-rate if no
+// source is set, otherwise a cached provider reading the rate from the file or HTTP(S) URL.
+func NewRateProvider(cfg *config.Config) RateProvider {
+	if cfg.RateSource == "" {
+		return NewStaticRateProvider(cfg.ConversionRate)
+	}
+	return NewCachedRateProvider(NewRemoteRateProvider(cfg.RateSource, nil), cfg.RateCacheTTL, cfg.RateMaxAge)
+}
+
+// StaticRateProvider always provides the same rate.
+type StaticRateProvider struct {
+	rate uint64
+}
+
+func NewStaticRateProvider(rate uint64) *StaticRateProvider {
+	return &StaticRateProvider{rate: rate}
+}
+
+func (srp *StaticRateProvider) GetRate() (rate types.ConversionRate, err error) {
+	rate.Rate = srp.rate
+	rate.Timestamp = time.Now().Unix()
+	return
+}
+
+// RemoteRateProvider reads a JSON encoded types.ConversionRate from a local file or an HTTP(S) URL.
+type RemoteRateProvider struct {
+	source string
+	client *http.Client
+}
+
+func NewRemoteRateProvider(source string, client *http.Client) *RemoteRateProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RemoteRateProvider{source: source, client: client}
+}
+
+func (rrp *RemoteRateProvider) GetRate() (rate types.ConversionRate, err error) {
+	var body []byte
+	if strings.HasPrefix(rrp.source, "http://") || strings.HasPrefix(rrp.source, "https://") {
+		body, err = rrp.fetch()
+	} else {
+		body, err = os.ReadFile(rrp.source)
+	}
+	if err != nil {
+		err = fmt.Errorf("reading conversion rate from %s: %w", rrp.source, err)
+		return
+	}
+
+	err = json.Unmarshal(body, &rate)
+	if err != nil {
+		err = fmt.Errorf("decoding conversion rate from %s: %w", rrp.source, err)
+		return
+	}
+	if rate.Rate == 0 {
+		err = fmt.Errorf("conversion rate from %s must not be 0", rrp.source)
+	}
+	return
+}
+
+func (rrp *RemoteRateProvider) fetch() (body []byte, err error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, rrp.source, nil)
+	if err != nil {
+		return
+	}
+	resp, err := rrp.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = errors.New(resp.Status)
+		return
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// CachedRateProvider caches the rate of another provider for ttl and refuses rates older than maxAge.
+// If the provider fails, the cached rate is used for as long as it is not older than maxAge.
+type CachedRateProvider struct {
+	provider  RateProvider
+	ttl       time.Duration
+	maxAge    time.Duration
+	mu        sync.Mutex
+	rate      types.ConversionRate
+	fetchedAt time.Time
+}
+
+func NewCachedRateProvider(provider RateProvider, ttl time.Duration, maxAge time.Duration) *CachedRateProvider {
+	return &CachedRateProvider{provider: provider, ttl: ttl, maxAge: maxAge}
+}
+
+func (crp *CachedRateProvider) GetRate() (rate types.ConversionRate, err error) {
+	crp.mu.Lock()
+	defer crp.mu.Unlock()
+
+	if !crp.fetchedAt.IsZero() && time.Since(crp.fetchedAt) < crp.ttl {
+		return crp.checkAge(crp.rate)
+	}
+
+	fetched, err := crp.provider.GetRate()
+	if err == nil {
+		fetched, err = crp.checkAge(fetched)
+	}
+	if err != nil {
+		if crp.fetchedAt.IsZero() {
+			return
+		}
+		// fall back to the cached rate while it is recent enough
+		cached, ageErr := crp.checkAge(crp.rate)
+		if ageErr != nil {
+			err = errors.Join(err, ageErr)
+			return
+		}
+		return cached, nil
+	}
+
+	crp.rate = fetched
+	crp.fetchedAt = time.Now()
+	return fetched, nil
+}
+
+func (crp *CachedRateProvider) checkAge(rate types.ConversionRate) (types.ConversionRate, error) {
+	age := time.Since(time.Unix(rate.Timestamp, 0))
+	if age > crp.maxAge {
+		return rate, fmt.Errorf("conversion rate is stale: %s old, max age is %s", age.Truncate(time.Second), crp.maxAge)
+	}
+	return rate, nil
+}
@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/service"
+	"github.com/rddl-network/rddl-2-plmnt-service/testutil"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticRateProvider(t *testing.T) {
+	rate, err := service.NewStaticRateProvider(100).GetRate()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), rate.Rate)
+}
+
+func TestRemoteRateProvider(t *testing.T) {
+	expected := types.ConversionRate{Rate: 250, Timestamp: time.Now().Unix()}
+	body, err := json.Marshal(expected)
+	assert.NoError(t, err)
+
+	// local stub of a rate feed
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rate" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, err := w.Write(body)
+		assert.NoError(t, err)
+	}))
+	defer mockServer.Close()
+
+	rate, err := service.NewRemoteRateProvider(mockServer.URL+"/rate", mockServer.Client()).GetRate()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, rate)
+
+	path := filepath.Join(t.TempDir(), "rate.json")
+	err = os.WriteFile(path, body, 0o600)
+	assert.NoError(t, err)
+	rate, err = service.NewRemoteRateProvider(path, nil).GetRate()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, rate)
+
+	err = os.WriteFile(path, []byte(`{"rate": 0}`), 0o600)
+	assert.NoError(t, err)
+	_, err = service.NewRemoteRateProvider(path, nil).GetRate()
+	assert.Error(t, err)
+
+	_, err = service.NewRemoteRateProvider(mockServer.URL+"/missing", mockServer.Client()).GetRate()
+	assert.Error(t, err)
+}
+
+func TestCachedRateProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	providerMock := testutil.NewMockRateProvider(ctrl)
+	fresh := types.ConversionRate{Rate: 100, Timestamp: time.Now().Unix()}
+	stale := types.ConversionRate{Rate: 100, Timestamp: time.Now().Add(-2 * time.Hour).Unix()}
+
+	// rates are cached for the ttl
+	providerMock.EXPECT().GetRate().Return(fresh, nil).Times(1)
+	crp := service.NewCachedRateProvider(providerMock, time.Hour, time.Hour)
+	for i := 0; i < 3; i++ {
+		rate, err := crp.GetRate()
+		assert.NoError(t, err)
+		assert.Equal(t, fresh, rate)
+	}
+
+	// stale rates are refused
+	providerMock.EXPECT().GetRate().Return(stale, nil)
+	_, err := service.NewCachedRateProvider(providerMock, time.Hour, time.Hour).GetRate()
+	assert.ErrorContains(t, err, "stale")
+
+	// the cached rate is used while the provider is unavailable
+	providerMock.EXPECT().GetRate().Return(fresh, nil)
+	providerMock.EXPECT().GetRate().Return(types.ConversionRate{}, errors.New("unavailable"))
+	crp = service.NewCachedRateProvider(providerMock, 0, time.Hour)
+	_, err = crp.GetRate()
+	assert.NoError(t, err)
+	rate, err := crp.GetRate()
+	assert.NoError(t, err)
+	assert.Equal(t, fresh, rate)
+}
+
+func TestNewRateProvider(t *testing.T) {
+	cfg := *config.DefaultConfig()
+	assert.IsType(t, &service.StaticRateProvider{}, service.NewRateProvider(&cfg))
+	cfg.RateSource = "https://example.com/rate"
+	assert.IsType(t, &service.CachedRateProvider{}, service.NewRateProvider(&cfg))
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/planetmint/planetmint-go/util"
+	daotypes "github.com/planetmint/planetmint-go/x/dao/types"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+)
+
+// reconciliationIssueKinds are the kinds of issues counted by the reconciliation metric.
+var reconciliationIssueKinds = []types.ReconciliationIssueKind{types.IssueUnmatchedReceipt, types.IssueDoubleMint,
+	types.IssueAmountMismatch, types.IssueBeneficiaryMismatch}
+
+// latestReconciliation keeps the report of the last periodic reconciliation.
+type latestReconciliation struct {
+	mu     sync.Mutex
+	report *types.ReconciliationReport
+}
+
+// receipt is a Liquid transaction of the accepted-asset received by an address of the wallet.
+type receipt struct {
+	address string
+	txID    string
+	amount  uint64
+	time    int64
+}
+
+// recordedDeposit is a deposit recorded by the service together with its conversion.
+type recordedDeposit struct {
+	liquidAddress string
+	beneficiary   string
+	deposit       types.Deposit
+}
+
+// Reconcile matches the receipts of the accepted-asset by the wallet within [from, to] against the mint
+// requests on Planetmint and the deposits recorded with the active and archived conversions. A receipt is
+// matched if it got minted once, to the beneficiary of its conversion and with the amount
+// GetConversionWithRemainder yields for the received RDDL. Deposits without a recorded conversion rate
+// are checked against the current rate. Receipts the service did not mint for a recorded reason, e.g.
+// dust or pending deposits, are counted as unminted.
+func (r2p *R2PService) Reconcile(ctx context.Context, from int64, to int64) (report types.ReconciliationReport, err error) {
+	report = types.ReconciliationReport{
+		From:      from,
+		To:        to,
+		CreatedAt: time.Now().Unix(),
+		Unminted:  make(map[types.ConversionState]int),
+		Issues:    []types.ReconciliationIssue{},
+	}
+	receipts, err := r2p.listReceipts(ctx, from, to)
+	if err != nil {
+		return
+	}
+	deposits, err := r2p.recordedDeposits()
+	if err != nil {
+		return
+	}
+
+	var currentRate uint64
+	rateOf := func(deposit types.Deposit) (rate uint64, err error) {
+		if deposit.ConversionRate != 0 {
+			return deposit.ConversionRate, nil
+		}
+		if currentRate == 0 {
+			conversionRate, err := r2p.rateProvider.GetRate()
+			if err != nil {
+				return 0, fmt.Errorf("fetching conversion rate: %w", err)
+			}
+			currentRate = conversionRate.Rate
+		}
+		return currentRate, nil
+	}
+
+	// a transaction paying several receive addresses is minted once
+	mints := make(map[string]*daotypes.MintRequest)
+	for _, rc := range receipts {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		mint, fetched := mints[rc.txID]
+		if !fetched {
+			mint, err = r2p.getMintRequest(ctx, rc.txID)
+			if err != nil {
+				return
+			}
+			mints[rc.txID] = mint
+			if mint != nil {
+				report.MintedPLMNT += mint.Amount
+			}
+		}
+
+		var issues []types.ReconciliationIssue
+		var unminted types.ConversionState
+		issues, unminted, err = reconcileReceipt(rc, deposits[rc.txID], mint, rateOf)
+		if err != nil {
+			return
+		}
+		report.Receipts++
+		report.ReceivedRDDL += rc.amount
+		report.Issues = append(report.Issues, issues...)
+		if unminted != "" {
+			report.Unminted[unminted]++
+		} else if len(issues) == 0 {
+			report.Matched++
+		}
+	}
+	return
+}
+
+// reconcileReceipt returns the issues of the receipt, or the state of its deposit if the service did not
+// mint it for a recorded reason.
+func reconcileReceipt(rc receipt, records []recordedDeposit, mint *daotypes.MintRequest,
+	rateOf func(types.Deposit) (uint64, error)) (issues []types.ReconciliationIssue, unminted types.ConversionState, err error) {
+	issue := types.ReconciliationIssue{LiquidTxID: rc.txID, LiquidAddress: rc.address, RDDLAmount: rc.amount}
+	if mint != nil {
+		issue.PlanetmintBeneficiary = mint.Beneficiary
+		issue.MintedPLMNTAmount = mint.Amount
+	}
+	report := func(kind types.ReconciliationIssueKind, detail string) {
+		issue.Kind = kind
+		issue.Detail = detail
+		issues = append(issues, issue)
+	}
+
+	record, minted := matchDeposit(rc, records)
+	if record == nil {
+		if mint != nil {
+			report(types.IssueUnmatchedReceipt, "minted on planetmint but not recorded by the service")
+			return
+		}
+		report(types.IssueUnmatchedReceipt, "neither minted nor recorded by the service")
+		return
+	}
+	issue.PlanetmintBeneficiary = record.beneficiary
+	issue.State = record.deposit.State
+
+	if mint == nil {
+		if record.deposit.State == types.StateMintConfirmed {
+			report(types.IssueUnmatchedReceipt, "recorded as minted but there is no mint request on planetmint")
+			return
+		}
+		return nil, record.deposit.State, nil
+	}
+
+	if record.deposit.RefundTxID != "" {
+		report(types.IssueDoubleMint, "minted on planetmint and refunded with tx "+record.deposit.RefundTxID)
+	}
+	if record.deposit.State == types.StateCredited {
+		report(types.IssueDoubleMint, "minted on planetmint and credited to the remainder ledger")
+	}
+	if minted > 1 {
+		report(types.IssueDoubleMint, fmt.Sprintf("recorded as minted by %d deposits", minted))
+	}
+	if mint.Beneficiary != record.beneficiary {
+		report(types.IssueBeneficiaryMismatch, "minted to "+mint.Beneficiary+" instead of "+record.beneficiary)
+	}
+
+	rate, err := rateOf(record.deposit)
+	if err != nil {
+		return
+	}
+	issue.ExpectedPLMNTAmount, _ = GetConversionWithRemainder(rc.amount, rate, record.deposit.RemainderCredit)
+	if record.deposit.RDDLAmount != rc.amount {
+		report(types.IssueAmountMismatch, fmt.Sprintf("recorded %d RDDL but received %d", record.deposit.RDDLAmount, rc.amount))
+	}
+	if mint.Amount != issue.ExpectedPLMNTAmount {
+		report(types.IssueAmountMismatch, fmt.Sprintf("minted %d PLMNT instead of %d", mint.Amount, issue.ExpectedPLMNTAmount))
+	}
+	return
+}
+
+// matchDeposit returns the deposit recorded for the receipt and the number of deposits recording a mint
+// broadcast of its transaction. A deposit is recorded with the receive address of its conversion, the
+// only deposit of a transaction matches as well.
+func matchDeposit(rc receipt, records []recordedDeposit) (record *recordedDeposit, minted int) {
+	for i := range records {
+		if records[i].liquidAddress == rc.address {
+			record = &records[i]
+		}
+		state := records[i].deposit.State
+		if records[i].deposit.PlanetmintTxHash != "" && (state == types.StateMintBroadcast || state == types.StateMintConfirmed) {
+			minted++
+		}
+	}
+	if record == nil && len(records) == 1 {
+		record = &records[0]
+	}
+	return
+}
+
+// listTransactionsPage is the number of wallet transactions fetched per listtransactions call.
+const listTransactionsPage = 1000
+
+// listReceipts returns the receipts of the accepted-asset by the addresses of the wallet within [from, to].
+// The amount of a receipt is the amount its transaction paid to the address, like the amount of a deposit.
+// listtransactions pages back from the latest transaction, so the listing stops with the first page that
+// reaches back before the range.
+func (r2p *R2PService) listReceipts(ctx context.Context, from int64, to int64) (receipts []receipt, err error) {
+	cfg := config.GetConfig()
+	// a transaction may pay an address with several outputs
+	indexes := make(map[[2]string]int)
+	// transactions arriving between two calls shift the pages, so an output may be listed twice
+	type outpoint struct {
+		txID string
+		vout uint32
+	}
+	listed := make(map[outpoint]bool)
+	for skip := 0; ; skip += listTransactionsPage {
+		txs, err := r2p.eClient.ListTransactions(ctx, cfg.GetElementsURL(),
+			[]string{`"*"`, strconv.Itoa(listTransactionsPage), strconv.Itoa(skip), "true"})
+		if err != nil {
+			return nil, fmt.Errorf("listing wallet transactions: %w", err)
+		}
+		for _, tx := range txs {
+			if tx.Category != categoryReceive || tx.Asset != cfg.AcceptedAsset || tx.Time < from || tx.Time > to {
+				continue
+			}
+			if listed[outpoint{tx.TxID, tx.Vout}] {
+				continue
+			}
+			listed[outpoint{tx.TxID, tx.Vout}] = true
+			key := [2]string{tx.TxID, tx.Address}
+			i, known := indexes[key]
+			if !known {
+				i = len(receipts)
+				indexes[key] = i
+				receipts = append(receipts, receipt{address: tx.Address, txID: tx.TxID, time: tx.Time})
+			}
+			receipts[i].amount += util.RDDLToken2Uint(tx.Amount)
+		}
+		if len(txs) < listTransactionsPage || txs[0].Time < from {
+			return receipts, nil
+		}
+	}
+}
+
+// recordedDeposits returns the deposits of the active and archived conversions by Liquid transaction.
+func (r2p *R2PService) recordedDeposits() (deposits map[string][]recordedDeposit, err error) {
+	deposits = make(map[string][]recordedDeposit)
+	iter := r2p.db.NewIterator(nil, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if !isConversionKey(iter.Key()) && !bytes.HasPrefix(iter.Key(), archivePrefix) {
+			continue
+		}
+		req, err := decodeConversionRequest(iter.Value())
+		if err != nil {
+			r2p.logger.Error("msg", "failed to unmarshal entry", "key", string(iter.Key()), "error", err)
+			continue
+		}
+		for _, deposit := range req.Deposits {
+			deposits[deposit.LiquidTxID] = append(deposits[deposit.LiquidTxID],
+				recordedDeposit{liquidAddress: req.ConfidentialAddress, beneficiary: req.PlanetmintAddress, deposit: deposit})
+		}
+	}
+	err = iter.Error()
+	return
+}
+
+// getMintRequest returns the mint request of the Liquid transaction on Planetmint, nil if there is none.
+func (r2p *R2PService) getMintRequest(ctx context.Context, txID string) (mint *daotypes.MintRequest, err error) {
+	res, err := r2p.pmClient.CheckMintRequest(ctx, txID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching mint request of tx %s: %w", txID, err)
+	}
+	if res == nil {
+		return
+	}
+	return res.MintRequest, nil
+}
+
+// reconcile is the periodic reconciliation of the receipts within the last reconciliation-window. The
+// report is kept as the latest reconciliation and its issues are logged.
+func (r2p *R2PService) reconcile(ctx context.Context) (err error) {
+	to := time.Now().Unix()
+	from := to - int64(config.GetConfig().ReconciliationWindow.Seconds())
+	report, err := r2p.Reconcile(ctx, from, to)
+	if err != nil {
+		r2p.log(ctx).Error("msg", "reconciliation failed", "error", err)
+		return
+	}
+
+	r2p.latestReconciliation.mu.Lock()
+	r2p.latestReconciliation.report = &report
+	r2p.latestReconciliation.mu.Unlock()
+
+	counts := make(map[types.ReconciliationIssueKind]int)
+	for _, issue := range report.Issues {
+		counts[issue.Kind]++
+		r2p.log(ctx).Warn("msg", "reconciliation issue", "kind", issue.Kind, "txid", issue.LiquidTxID,
+			"liquid-address", issue.LiquidAddress, "beneficiary", issue.PlanetmintBeneficiary, "state", issue.State, "detail", issue.Detail)
+	}
+	for _, kind := range reconciliationIssueKinds {
+		reconciliationIssues.WithLabelValues(string(kind)).Set(float64(counts[kind]))
+	}
+	r2p.log(ctx).Info("msg", "reconciliation completed", "from", from, "to", to, "receipts", report.Receipts,
+		"matched", report.Matched, "issues", len(report.Issues))
+	return
+}
+
+// GetLatestReconciliation returns the report of the last periodic reconciliation, ok is false if none
+// completed yet.
+func (r2p *R2PService) GetLatestReconciliation() (report types.ReconciliationReport, ok bool) {
+	r2p.latestReconciliation.mu.Lock()
+	defer r2p.latestReconciliation.mu.Unlock()
+	if r2p.latestReconciliation.report == nil {
+		return
+	}
+	return *r2p.latestReconciliation.report, true
+}
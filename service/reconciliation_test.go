@@ -0,0 +1,185 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	daotypes "github.com/planetmint/planetmint-go/x/dao/types"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/service"
+	"github.com/rddl-network/rddl-2-plmnt-service/testutil"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcile(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, _, pmClientMock, eClientMock := setupR2PService(t)
+	now := time.Now().Unix()
+
+	receipts := []struct {
+		address string
+		txID    string
+		amount  float64
+		time    int64
+		mint    *daotypes.MintRequest
+		deposit *types.Deposit
+	}{
+		{
+			address: "addr-matched", txID: "tx-matched", amount: 2, time: now,
+			mint:    &daotypes.MintRequest{Beneficiary: testutil.PlanetmintAddress, Amount: 200, LiquidTxHash: "tx-matched"},
+			deposit: &types.Deposit{RDDLAmount: 200000000, ConversionRate: 100, PLMNTAmount: 200, PlanetmintTxHash: "HASH1", State: types.StateMintConfirmed},
+		},
+		{
+			address: "addr-refunded", txID: "tx-refunded", amount: 1, time: now,
+			mint:    &daotypes.MintRequest{Beneficiary: testutil.PlanetmintAddress, Amount: 100, LiquidTxHash: "tx-refunded"},
+			deposit: &types.Deposit{RDDLAmount: 100000000, ConversionRate: 100, RefundTxID: "REFUND", State: types.StateRefunded},
+		},
+		{
+			address: "addr-mismatch", txID: "tx-mismatch", amount: 1, time: now,
+			mint:    &daotypes.MintRequest{Beneficiary: testutil.PlanetmintAddress, Amount: 150, LiquidTxHash: "tx-mismatch"},
+			deposit: &types.Deposit{RDDLAmount: 100000000, ConversionRate: 100, PLMNTAmount: 150, PlanetmintTxHash: "HASH2", State: types.StateMintConfirmed},
+		},
+		{
+			address: "addr-dust", txID: "tx-dust", amount: 0.00001, time: now,
+			deposit: &types.Deposit{RDDLAmount: 1000, State: types.StateDust},
+		},
+		{address: "addr-unknown", txID: "tx-unknown", amount: 0.5, time: now},
+		// receipts outside of the range are not reconciled
+		{address: "addr-old", txID: "tx-old", amount: 3, time: now - 3600},
+	}
+
+	// the refund of tx-refunded and payments of other assets are no receipts
+	txs := []types.WalletTransaction{
+		{Address: "addr-foreign", Category: "receive", Amount: 5, Asset: "foreign-asset", TxID: "tx-foreign", Time: now},
+		{Address: testutil.UnconfidentialAddr, Category: "send", Amount: -1, Asset: cfg.AcceptedAsset, TxID: "REFUND", Time: now},
+	}
+	for _, rc := range receipts {
+		txs = append(txs, types.WalletTransaction{Address: rc.address, Category: "receive", Amount: rc.amount, Asset: cfg.AcceptedAsset, TxID: rc.txID, Time: rc.time})
+		if rc.time < now {
+			continue
+		}
+		var res *daotypes.QueryGetMintRequestsByHashResponse
+		if rc.mint != nil {
+			res = &daotypes.QueryGetMintRequestsByHashResponse{MintRequest: rc.mint}
+		}
+		pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), rc.txID).Return(res, nil)
+		if rc.deposit == nil {
+			continue
+		}
+		deposit := *rc.deposit
+		deposit.LiquidTxID = rc.txID
+		conversion := service.ConversionRequest{ConfidentialAddress: rc.address, PlanetmintAddress: testutil.PlanetmintAddress,
+			State: deposit.State, Deposits: []types.Deposit{deposit}}
+		assert.NoError(t, r2p.StoreConversionRequest(conversion))
+	}
+	eClientMock.EXPECT().ListTransactions(gomock.Any(), gomock.Any(), []string{`"*"`, "1000", "0", "true"}).Return(txs, nil)
+
+	report, err := r2p.Reconcile(context.Background(), now-60, now+60)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, report.Receipts)
+	assert.Equal(t, 1, report.Matched)
+	assert.Equal(t, map[types.ConversionState]int{types.StateDust: 1}, report.Unminted)
+	assert.Equal(t, uint64(450001000), report.ReceivedRDDL)
+	assert.Equal(t, uint64(450), report.MintedPLMNT)
+
+	issues := make(map[string]types.ReconciliationIssue)
+	for _, issue := range report.Issues {
+		issues[issue.LiquidTxID] = issue
+	}
+	assert.Len(t, report.Issues, 3)
+	assert.Equal(t, types.IssueUnmatchedReceipt, issues["tx-unknown"].Kind)
+	assert.Equal(t, uint64(50000000), issues["tx-unknown"].RDDLAmount)
+	assert.Equal(t, types.IssueDoubleMint, issues["tx-refunded"].Kind)
+	assert.Equal(t, types.StateRefunded, issues["tx-refunded"].State)
+	assert.Equal(t, types.IssueAmountMismatch, issues["tx-mismatch"].Kind)
+	assert.Equal(t, uint64(100), issues["tx-mismatch"].ExpectedPLMNTAmount)
+	assert.Equal(t, uint64(150), issues["tx-mismatch"].MintedPLMNTAmount)
+	assert.Equal(t, "addr-mismatch", issues["tx-mismatch"].LiquidAddress)
+}
+
+func TestReconcileListsPages(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, _, pmClientMock, eClientMock := setupR2PService(t)
+	now := time.Now().Unix()
+
+	page := func(time int64, receipt types.WalletTransaction) (txs []types.WalletTransaction) {
+		for i := range 999 {
+			txs = append(txs, types.WalletTransaction{Category: "send", Amount: -1, Asset: cfg.AcceptedAsset, TxID: fmt.Sprintf("send%d", i), Time: time})
+		}
+		return append(txs, receipt)
+	}
+
+	// the second page reaches back before the range, so the listing stops there
+	latest := page(now, types.WalletTransaction{Address: "addr-new", Category: "receive", Amount: 1, Asset: cfg.AcceptedAsset, TxID: "tx-new", Time: now})
+	earlier := append([]types.WalletTransaction{{Address: "addr-old", Category: "receive", Amount: 3, Asset: cfg.AcceptedAsset, TxID: "tx-old", Time: now - 3600}},
+		page(now-60, types.WalletTransaction{Address: "addr-split", Category: "receive", Amount: 0.5, Asset: cfg.AcceptedAsset, TxID: "tx-split", Time: now - 60})[1:]...)
+	earlier = append(earlier, types.WalletTransaction{Address: "addr-split", Category: "receive", Amount: 0.25, Asset: cfg.AcceptedAsset, TxID: "tx-split", Vout: 1, Time: now - 60})
+	// a transaction arriving between the calls shifts the receipt of the first page into the second one
+	earlier = append(earlier, latest[len(latest)-1])
+	eClientMock.EXPECT().ListTransactions(gomock.Any(), gomock.Any(), []string{`"*"`, "1000", "0", "true"}).Return(latest, nil)
+	eClientMock.EXPECT().ListTransactions(gomock.Any(), gomock.Any(), []string{`"*"`, "1000", "1000", "true"}).Return(earlier, nil)
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), "tx-new").Return(nil, nil)
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), "tx-split").Return(nil, nil)
+
+	report, err := r2p.Reconcile(context.Background(), now-120, now+60)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Receipts)
+	// the outputs paying the same address add up to one receipt, outputs listed twice are counted once
+	assert.Equal(t, uint64(175000000), report.ReceivedRDDL)
+}
+
+func TestReconciliationRoutes(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.AdminToken = "secret"
+	defer func() { cfg.AdminToken = "" }()
+	r2p, router, pmClientMock, eClientMock := setupR2PService(t)
+
+	w := getReconciliation(t, router, "/admin/reconciliation/latest")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// the periodic reconciliation keeps its report and counts the issues
+	txs := []types.WalletTransaction{{Address: testutil.ConfidentialAddr, Category: "receive", Amount: 2, Asset: cfg.AcceptedAsset,
+		TxID: testutil.Deposit1Of1Tx.TxID, Time: time.Now().Unix()}}
+	eClientMock.EXPECT().ListTransactions(gomock.Any(), gomock.Any(), gomock.Any()).Return(txs, nil)
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), testutil.Deposit1Of1Tx.TxID).Return(nil, nil)
+	r2p.StartReconciliationPass()
+	assert.Eventually(t, func() bool { return r2p.GetPassStatus()["reconciliation"].LastSuccess != 0 }, time.Second, 10*time.Millisecond)
+
+	w = getReconciliation(t, router, "/admin/reconciliation/latest")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report types.ReconciliationReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 1, report.Receipts)
+	assert.Len(t, report.Issues, 1)
+	assert.Equal(t, report.To-int64(cfg.ReconciliationWindow.Seconds()), report.From)
+	assert.Equal(t, float64(1), scrapeMetrics(t, router)[`r2p_reconciliation_issues{kind="unmatched-receipt"}`])
+
+	// on demand over a given range
+	eClientMock.EXPECT().ListTransactions(gomock.Any(), gomock.Any(), gomock.Any()).Return(txs, nil)
+	w = getReconciliation(t, router, "/admin/reconciliation?from=100&to=200")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, int64(100), report.From)
+	assert.Equal(t, 0, report.Receipts)
+	assert.Empty(t, report.Issues)
+
+	w = getReconciliation(t, router, "/admin/reconciliation?from=200&to=100")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func getReconciliation(t *testing.T, router http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, target, nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	router.ServeHTTP(w, req)
+	return w
+}
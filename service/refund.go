@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/planetmint/planetmint-go/util"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+)
+
+// refundAddressPattern is a sanity check for Liquid addresses, the wallet validates them on refund.
+var refundAddressPattern = regexp.MustCompile(`^[a-zA-Z0-9]{26,128}$`)
+
+// IsValidRefundAddress reports whether the address looks like a Liquid address.
+func IsValidRefundAddress(address string) bool {
+	return refundAddressPattern.MatchString(address)
+}
+
+// refundableDeposits returns the deposits that can be sent back to the requester: deposits that are
+// dust, failed or under review, confirmed deposits if the whole conversion is under review and the
+// unconverted deposits of a late deposit.
+func (req *ConversionRequest) refundableDeposits() (deposits []*types.Deposit) {
+	for i := range req.Deposits {
+		deposit := &req.Deposits[i]
+		if slices.Contains(refundableStates, deposit.State) ||
+			(req.State == types.StateNeedsReview && deposit.State == types.StateConfirmed) ||
+			(req.State == types.StateLateDeposit && deposit.State == types.StateFundsDetected) {
+			deposits = append(deposits, deposit)
+		}
+	}
+	return
+}
+
+// isRefundable reports whether the conversion waits for a refund.
+func (req *ConversionRequest) isRefundable() bool {
+	return !isProcessable(req.State) && len(req.refundableDeposits()) > 0
+}
+
+// RefundConversion sends the RDDL of all refundable deposits back to the refund address of the
+// conversion in a single transaction. Deposits that got minted in the meantime are not refunded.
+// The refund is refused while a deposit has fewer than the configured confirmations, as its transaction
+// may still be conflicted. The refund transaction id is recorded with every refunded deposit and the
+// outcome is persisted.
+func (r2p *R2PService) RefundConversion(ctx context.Context, conversion *ConversionRequest) (err error) {
+	if conversion.RefundAddress == "" {
+		return fmt.Errorf("conversion %s has no refund address", conversion.ConfidentialAddress)
+	}
+	deposits := conversion.refundableDeposits()
+	if len(deposits) == 0 {
+		return fmt.Errorf("conversion %s has no deposits to refund", conversion.ConfidentialAddress)
+	}
+	err = r2p.checkRefundConfirmations(ctx, conversion, deposits)
+	if err != nil {
+		return
+	}
+
+	var refunds []*types.Deposit
+	var amount uint64
+	for _, deposit := range deposits {
+		// a failed mint may still have made it to planetmint
+		code, err := r2p.checkMintRequest(ctx, deposit.LiquidTxID)
+		if err != nil {
+			return fmt.Errorf("error while checking mint request: %w code: %d for tx %s", err, code, deposit.LiquidTxID)
+		}
+		if code == http.StatusConflict {
+			err = transitionDeposit(deposit, types.StateMintConfirmed)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		refunds = append(refunds, deposit)
+		amount += deposit.RDDLAmount
+	}
+
+	if amount > 0 {
+		cfg := config.GetConfig()
+		txID, err := r2p.eClient.SendToAddress(ctx, cfg.GetElementsURL(), []string{
+			`"` + conversion.RefundAddress + `"`,
+			util.UintValueToRDDLTokenString(amount),
+			`""`,
+			`""`,
+			"false",
+			"true",
+			"null",
+			`"unset"`,
+			"false",
+			`"` + cfg.AcceptedAsset + `"`,
+		})
+		if err != nil {
+			r2p.log(ctx).Error("msg", "refunding failed", "amount", amount, "refund-address", conversion.RefundAddress, "error", err)
+			return errors.New("error while refunding " + strconv.FormatUint(amount, 10) + " to " + conversion.RefundAddress + " for " + conversion.ConfidentialAddress + ": " + err.Error())
+		}
+		r2p.log(ctx).Info("msg", "conversion refunded", "amount", amount, "refund-address", conversion.RefundAddress, "refund-txid", txID)
+		for _, deposit := range refunds {
+			deposit.RefundTxID = txID
+		}
+	}
+
+	// the refund is sent at this point, so the outcome is stored even if a transition fails
+	var errs []error
+	for _, deposit := range refunds {
+		errs = append(errs, transitionDeposit(deposit, types.StateRefunded))
+	}
+	errs = append(errs, conversion.updateState())
+	conversion.LastError = ""
+	if err = errors.Join(errs...); err != nil {
+		conversion.LastError = err.Error()
+	}
+	errs = append(errs, r2p.storeConversionRequest(conversion))
+	return errors.Join(errs...)
+}
+
+// checkRefundConfirmations re-fetches the confirmations of deposits that were not confirmed yet, e.g. late
+// deposits, and fails unless all deposits have the configured number of confirmations.
+func (r2p *R2PService) checkRefundConfirmations(ctx context.Context, conversion *ConversionRequest, deposits []*types.Deposit) (err error) {
+	cfg := config.GetConfig()
+	for _, deposit := range deposits {
+		if deposit.State != types.StateFundsDetected && deposit.Confirmations >= uint64(cfg.Confirmations) {
+			continue
+		}
+		tx, err := r2p.eClient.GetTransaction(ctx, cfg.GetElementsURL(), []string{`"` + deposit.LiquidTxID + `"`})
+		if err != nil {
+			r2p.log(ctx).Error("msg", "fetching transaction failed", "txid", deposit.LiquidTxID, "error", err)
+			return errors.New("error: fetching tx " + deposit.LiquidTxID + " received by " + conversion.ConfidentialAddress + " : " + err.Error())
+		}
+		deposit.Confirmations = uint64(max(tx.Confirmations, 0))
+		if deposit.Confirmations < uint64(cfg.Confirmations) {
+			r2p.log(ctx).Info("msg", "deposit waits for confirmations before it can be refunded", "txid", deposit.LiquidTxID,
+				"confirmations", deposit.Confirmations, "required", cfg.Confirmations)
+			return fmt.Errorf("deposit %s of conversion %s has %d of %d confirmations and cannot be refunded yet",
+				deposit.LiquidTxID, conversion.ConfidentialAddress, deposit.Confirmations, cfg.Confirmations)
+		}
+	}
+	return
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// remainderPrefix separates the per-beneficiary remainder ledger from the conversion requests.
+// Remainders are stored in 1e-8 PLMNT.
+var remainderPrefix = []byte("remainder/")
+
+func remainderKey(beneficiary string) []byte {
+	return append(bytes.Clone(remainderPrefix), beneficiary...)
+}
+
+// getRemainder returns the PLMNT fraction credited to the beneficiary by earlier conversions.
+func (r2p *R2PService) getRemainder(beneficiary string) (remainder uint64, err error) {
+	value, err := r2p.db.Get(remainderKey(beneficiary), nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return
+	}
+	return strconv.ParseUint(string(value), 10, 64)
+}
+
+// setRemainder adds the write of the beneficiary's remainder to the batch.
+func setRemainder(batch *leveldb.Batch, beneficiary string, remainder uint64) {
+	if remainder == 0 {
+		batch.Delete(remainderKey(beneficiary))
+		return
+	}
+	batch.Put(remainderKey(beneficiary), []byte(strconv.FormatUint(remainder, 10)))
+}
+
+// revertRemainders takes the remainder of the deposits that were not minted in the end, as they failed, got
+// refunded or their mint was only simulated in dry-run mode, back from the beneficiary's ledger and restores
+// the credit they consumed. Deposits that got
+// minted after all are booked again. The ledger update is added to the batch that stores the conversion.
+// The caller holds the lock of the beneficiary's ledger.
+func (r2p *R2PService) revertRemainders(convReq *ConversionRequest, batch *leveldb.Batch) (err error) {
+	var ledger uint64
+	read := false
+	for i := range convReq.Deposits {
+		deposit := &convReq.Deposits[i]
+		// only deposits whose amount is fixed changed the ledger
+		unminted := deposit.State == types.StateFailed || deposit.State == types.StateRefunded || deposit.State == types.StateSimulated
+		if deposit.PLMNTAmount == 0 || unminted == deposit.RemainderReverted {
+			continue
+		}
+		if !read {
+			if ledger, err = r2p.getRemainder(convReq.PlanetmintAddress); err != nil {
+				return
+			}
+			read = true
+		}
+		add, sub := deposit.Remainder, deposit.RemainderCredit
+		if unminted {
+			add, sub = sub, add
+		}
+		if ledger+add < sub {
+			// a later conversion already minted the fraction as part of a whole PLMNT
+			r2p.log(withConversion(context.Background(), *convReq)).Warn("msg", "remainder was already credited and cannot be reverted",
+				"txid", deposit.LiquidTxID, "ledger", ledger+add, "remainder", sub)
+			sub = ledger + add
+		}
+		ledger = ledger + add - sub
+		deposit.RemainderReverted = unminted
+	}
+	if read {
+		setRemainder(batch, convReq.PlanetmintAddress, ledger)
+	}
+	return
+}
+
+// GetRemainders returns the remainder ledger of all beneficiaries with an outstanding PLMNT fraction.
+func (r2p *R2PService) GetRemainders() (remainders []types.Remainder, err error) {
+	iter := r2p.db.NewIterator(util.BytesPrefix(remainderPrefix), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		remainder, err := strconv.ParseUint(string(iter.Value()), 10, 64)
+		if err != nil {
+			r2p.logger.Error("msg", "failed to parse remainder", "key", string(iter.Key()), "error", err)
+			continue
+		}
+		remainders = append(remainders, types.Remainder{
+			Beneficiary: string(bytes.TrimPrefix(iter.Key(), remainderPrefix)),
+			Remainder:   remainder,
+		})
+	}
+	err = iter.Error()
+	return
+}
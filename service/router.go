@@ -1,26 +1,114 @@
 package service
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rddl-network/rddl-2-plmnt-service/config"
 	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// requestIDHeader carries the ID of a request. IDs sent by the caller are kept so that a request can be
+// followed across services, otherwise a new one is assigned.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the length of request IDs sent by callers.
+const maxRequestIDLength = 64
+
 func (r2p *R2PService) configureRouter() {
-	r2p.router.Use(gin.Logger())
+	r2p.router.Use(r2p.logRequests)
 	r2p.router.Use(gin.Recovery())
 }
 
+// logRequests assigns every request an ID that is attached to the log lines of the request and returned
+// in the response, and logs the request once it is handled. Probes and metric scrapes are logged at debug
+// level.
+func (r2p *R2PService) logRequests(c *gin.Context) {
+	started := time.Now()
+	requestID := c.GetHeader(requestIDHeader)
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		requestID = newID()
+	}
+	c.Header(requestIDHeader, requestID)
+	ctx := withLogFields(c.Request.Context(), "request-id", requestID)
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+
+	keyvals := []interface{}{"msg", "request handled", "method", c.Request.Method, "path", c.Request.URL.Path,
+		"status", c.Writer.Status(), "duration", time.Since(started).String(), "client-ip", c.ClientIP()}
+	path := c.FullPath()
+	if path == "/healthz" || path == "/readyz" || path == "/metrics" {
+		r2p.log(ctx).Debug(keyvals...)
+		return
+	}
+	r2p.log(ctx).Info(keyvals...)
+}
+
 func (r2p *R2PService) registerRoutes() {
 	r2p.router.GET("/receiveaddress/:plmntaddress", r2p.getReceiveAddress)
+	r2p.router.GET("/conversion/:liquidaddress", r2p.getConversion)
+	r2p.router.GET("/passes", r2p.getPassStatus)
+	r2p.router.GET("/healthz", r2p.getLiveness)
+	r2p.router.GET("/readyz", r2p.getReadiness)
+	r2p.router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	admin := r2p.router.Group("/admin", r2p.requireAdminToken)
+	admin.POST("/conversion/:liquidaddress/approve", r2p.approveConversion)
+	admin.POST("/conversion/:liquidaddress/refund", r2p.refundConversion)
+	admin.GET("/late-deposits", r2p.getLateDeposits)
+	admin.GET("/archive", r2p.getArchivedConversions)
+	admin.GET("/remainders", r2p.getRemainders)
+	admin.GET("/reconciliation", r2p.getReconciliation)
+	admin.GET("/reconciliation/latest", r2p.getLatestReconciliation)
+	admin.GET("/conversions/export", r2p.exportConversions)
+}
+
+// requireAdminToken only lets requests with the configured admin-token as bearer token pass.
+// Admin endpoints are disabled if no admin-token is configured.
+func (r2p *R2PService) requireAdminToken(c *gin.Context) {
+	cfg := config.GetConfig()
+	if cfg.AdminToken == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin endpoints are disabled"})
+		return
+	}
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.AdminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+		return
+	}
+	c.Next()
 }
 
 func (r2p *R2PService) getReceiveAddress(c *gin.Context) {
 	cfg := config.GetConfig()
 	address := c.Param("plmntaddress")
 
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	ctx, span := tracer.Start(ctx, "GET /receiveaddress", trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrBeneficiary.String(address)))
+	defer func() {
+		span.SetAttributes(semconv.HTTPStatusCode(c.Writer.Status()))
+		if c.Writer.Status() >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, http.StatusText(c.Writer.Status()))
+		}
+		span.End()
+	}()
+
 	// is legit planetmint address?
 	valid, err := VerifyAddress(address)
 	if err != nil {
@@ -32,8 +120,29 @@ func (r2p *R2PService) getReceiveAddress(c *gin.Context) {
 		return
 	}
 
+	// the refund address is optional, without it unconvertible deposits stay in the service wallet
+	refundAddress := c.Query("refundaddress")
+	if refundAddress != "" && !IsValidRefundAddress(refundAddress) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid refund address"})
+		return
+	}
+
+	// the requester may shorten or extend the monitoring window within the configured bounds
+	ttl := cfg.AddressTTL
+	if value := c.Query("ttl"); value != "" {
+		ttl, err = time.ParseDuration(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ttl: " + err.Error()})
+			return
+		}
+		if ttl < cfg.MinAddressTTL || ttl > cfg.MaxAddressTTL {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ttl must be between " + cfg.MinAddressTTL.String() + " and " + cfg.MaxAddressTTL.String()})
+			return
+		}
+	}
+
 	// derive new receive address
-	confReceiveAddress, err := r2p.eClient.GetNewAddress(cfg.GetElementsURL(), []string{
+	confReceiveAddress, err := r2p.eClient.GetNewAddress(ctx, cfg.GetElementsURL(), []string{
 		``,
 	})
 	if err != nil {
@@ -41,15 +150,259 @@ func (r2p *R2PService) getReceiveAddress(c *gin.Context) {
 		return
 	}
 
+	span.SetAttributes(attrLiquidAddress.String(confReceiveAddress))
+
 	// store receive address - planetmint address pair
-	err = r2p.addConversionRequest(confReceiveAddress, address)
+	convReq, err := r2p.addConversionRequest(ctx, confReceiveAddress, address, refundAddress, ttl)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "storing addresses in DB: " + err.Error()})
 		return
 	}
+	r2p.log(withConversion(ctx, convReq)).Info("msg", "receive address issued", "expires-at", convReq.ExpiresAt)
 
 	var resBody types.ReceiveAddressResponse
+	resBody.ConversionID = convReq.ID
 	resBody.LiquidAddress = confReceiveAddress
 	resBody.PlanetmintBeneficiary = address
+	resBody.RefundAddress = refundAddress
+	resBody.ExpiresAt = convReq.ExpiresAt
+	addressesIssued.Inc()
 	c.JSON(http.StatusOK, resBody)
 }
+
+func (r2p *R2PService) getConversion(c *gin.Context) {
+	address := c.Param("liquidaddress")
+
+	convReq, err := r2p.GetConversionRequest(address)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		convReq, err = r2p.GetArchivedConversion(address)
+	}
+	if errors.Is(err, leveldb.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no conversion registered for address " + address})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reading conversion from DB: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, convReq.toResponse())
+}
+
+// completionRange returns the range of completion times given by the from and to query parameters, all
+// times by default. ok is false if the response was written already.
+func completionRange(c *gin.Context) (from int64, to int64, ok bool) {
+	from, err := strconv.ParseInt(c.DefaultQuery("from", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from timestamp: " + err.Error()})
+		return
+	}
+	to, err = strconv.ParseInt(c.DefaultQuery("to", strconv.FormatInt(math.MaxInt64, 10)), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to timestamp: " + err.Error()})
+		return
+	}
+	return from, to, true
+}
+
+func (r2p *R2PService) getArchivedConversions(c *gin.Context) {
+	from, to, ok := completionRange(c)
+	if !ok {
+		return
+	}
+
+	convReqs, err := r2p.GetArchivedConversions(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reading archive from DB: " + err.Error()})
+		return
+	}
+
+	resBody := make([]types.ConversionResponse, 0, len(convReqs))
+	for _, convReq := range convReqs {
+		resBody = append(resBody, convReq.toResponse())
+	}
+	c.JSON(http.StatusOK, resBody)
+}
+
+func (r2p *R2PService) getRemainders(c *gin.Context) {
+	remainders, err := r2p.GetRemainders()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reading remainders from DB: " + err.Error()})
+		return
+	}
+	if remainders == nil {
+		remainders = []types.Remainder{}
+	}
+	c.JSON(http.StatusOK, remainders)
+}
+
+func (r2p *R2PService) getPassStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, r2p.GetPassStatus())
+}
+
+func (r2p *R2PService) approveConversion(c *gin.Context) {
+	address := c.Param("liquidaddress")
+	unlock := r2p.entryLocks.lock(address)
+	defer unlock()
+
+	convReq, err := r2p.GetConversionRequest(address)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no conversion registered for address " + address})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reading conversion from DB: " + err.Error()})
+		return
+	}
+
+	// late deposits are converted right away, the outcome is stored by the conversion
+	if convReq.State == types.StateLateDeposit {
+		r2p.convertLateDeposit(c, convReq)
+		return
+	}
+
+	err = convReq.Approve()
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	err = r2p.storeConversionRequest(&convReq)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "storing conversion in DB: " + err.Error()})
+		return
+	}
+	r2p.log(withConversion(c.Request.Context(), convReq)).Info("msg", "conversion approved")
+
+	c.JSON(http.StatusOK, convReq.toResponse())
+}
+
+func (r2p *R2PService) refundConversion(c *gin.Context) {
+	address := c.Param("liquidaddress")
+	unlock := r2p.entryLocks.lock(address)
+	defer unlock()
+
+	convReq, err := r2p.GetConversionRequest(address)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no conversion registered for address " + address})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reading conversion from DB: " + err.Error()})
+		return
+	}
+
+	if !convReq.isRefundable() {
+		c.JSON(http.StatusConflict, gin.H{"error": "conversion " + address + " has no deposits to refund"})
+		return
+	}
+	if convReq.RefundAddress == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "conversion " + address + " has no refund address"})
+		return
+	}
+	err = r2p.RefundConversion(withConversion(c.Request.Context(), convReq), &convReq)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "refunding conversion: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, convReq.toResponse())
+}
+
+func (r2p *R2PService) convertLateDeposit(c *gin.Context, convReq ConversionRequest) {
+	address := convReq.ConfidentialAddress
+	err := r2p.ExecutePotentialConversion(c.Request.Context(), convReq)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "converting late deposit: " + err.Error()})
+		return
+	}
+	r2p.log(withConversion(c.Request.Context(), convReq)).Info("msg", "late deposit approved")
+
+	convReq, err = r2p.GetConversionRequest(address)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		convReq, err = r2p.GetArchivedConversion(address)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reading conversion from DB: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, convReq.toResponse())
+}
+
+func (r2p *R2PService) getLateDeposits(c *gin.Context) {
+	convReqs, err := r2p.GetLateDeposits()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reading late deposits from DB: " + err.Error()})
+		return
+	}
+
+	resBody := make([]types.ConversionResponse, 0, len(convReqs))
+	for _, convReq := range convReqs {
+		resBody = append(resBody, convReq.toResponse())
+	}
+	c.JSON(http.StatusOK, resBody)
+}
+
+// getReconciliation reconciles the receipts within [from, to], by default within the last
+// reconciliation-window.
+func (r2p *R2PService) getReconciliation(c *gin.Context) {
+	now := time.Now().Unix()
+	to, err := strconv.ParseInt(c.DefaultQuery("to", strconv.FormatInt(now, 10)), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to timestamp: " + err.Error()})
+		return
+	}
+	window := int64(config.GetConfig().ReconciliationWindow.Seconds())
+	from, err := strconv.ParseInt(c.DefaultQuery("from", strconv.FormatInt(to-window, 10)), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from timestamp: " + err.Error()})
+		return
+	}
+	if from > to {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+
+	report, err := r2p.Reconcile(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reconciling: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (r2p *R2PService) getLatestReconciliation(c *gin.Context) {
+	report, ok := r2p.GetLatestReconciliation()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no reconciliation completed yet"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// exportConversions streams the deposits of the conversions completed within [from, to] as CSV or JSON.
+// Errors after the export started can only be logged, they leave the response incomplete.
+func (r2p *R2PService) exportConversions(c *gin.Context) {
+	from, to, ok := completionRange(c)
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", types.ExportFormatCSV)
+	var export func(ctx context.Context, from int64, to int64, w io.Writer) error
+	if format == types.ExportFormatCSV {
+		c.Header("Content-Type", "text/csv")
+		export = r2p.exportCSV
+	} else if format == types.ExportFormatJSON {
+		c.Header("Content-Type", "application/json")
+		export = r2p.exportJSON
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be " + types.ExportFormatCSV + " or " + types.ExportFormatJSON})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="conversions.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	ctx := c.Request.Context()
+	if err := export(ctx, from, to, c.Writer); err != nil {
+		r2p.log(ctx).Error("msg", "exporting conversions failed", "error", err)
+	}
+}
@@ -7,11 +7,16 @@ import (
 	stdlog "log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	daotypes "github.com/planetmint/planetmint-go/x/dao/types"
 	log "github.com/rddl-network/go-utils/logger"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
 	"github.com/rddl-network/rddl-2-plmnt-service/service"
 	"github.com/rddl-network/rddl-2-plmnt-service/testutil"
 	"github.com/rddl-network/rddl-2-plmnt-service/types"
@@ -31,13 +36,16 @@ func TestGetReceiveAddressRoute(t *testing.T) {
 		db.Close()
 		stdlog.Fatal(err)
 	}
-	_ = service.NewR2PService(router, pmClientMock, eClientMock, db, log.GetLogger(log.DEBUG))
+	_ = service.NewR2PService(router, pmClientMock, eClientMock, service.NewStaticRateProvider(100), db, log.GetLogger(log.DEBUG))
 
-	eClientMock.EXPECT().GetNewAddress(gomock.Any(), gomock.Any()).Return(testutil.ConfidentialAddr, nil).AnyTimes()
+	eClientMock.EXPECT().GetNewAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ConfidentialAddr, nil).AnyTimes()
 
 	tests := []struct {
 		desc              string
 		planetmintAddress string
+		refundAddress     string
+		ttl               string
+		expiresIn         time.Duration
 		resBody           types.ReceiveAddressResponse
 		code              int
 		errorMsg          string
@@ -52,6 +60,46 @@ func TestGetReceiveAddressRoute(t *testing.T) {
 			code:     200,
 			errorMsg: "",
 		},
+		{
+			desc:              "valid request with refund address",
+			planetmintAddress: testutil.PlanetmintAddress,
+			refundAddress:     testutil.UnconfidentialAddr,
+			resBody: types.ReceiveAddressResponse{
+				LiquidAddress:         testutil.ConfidentialAddr,
+				PlanetmintBeneficiary: testutil.PlanetmintAddress,
+				RefundAddress:         testutil.UnconfidentialAddr,
+			},
+			code:     200,
+			errorMsg: "",
+		},
+		{
+			desc:              "valid request with ttl",
+			planetmintAddress: testutil.PlanetmintAddress,
+			ttl:               "2h",
+			expiresIn:         2 * time.Hour,
+			resBody: types.ReceiveAddressResponse{
+				LiquidAddress:         testutil.ConfidentialAddr,
+				PlanetmintBeneficiary: testutil.PlanetmintAddress,
+			},
+			code:     200,
+			errorMsg: "",
+		},
+		{
+			desc:              "ttl out of bounds",
+			planetmintAddress: testutil.PlanetmintAddress,
+			ttl:               "72h",
+			resBody:           types.ReceiveAddressResponse{},
+			code:              400,
+			errorMsg:          "{\"error\":\"ttl must be between 1h0m0s and 48h0m0s\"}",
+		},
+		{
+			desc:              "Invalid refund address",
+			planetmintAddress: testutil.PlanetmintAddress,
+			refundAddress:     "tex1q\"",
+			resBody:           types.ReceiveAddressResponse{},
+			code:              400,
+			errorMsg:          "{\"error\":\"Invalid refund address\"}",
+		},
 		{
 			desc:              "missing request fields",
 			planetmintAddress: "",
@@ -72,7 +120,15 @@ func TestGetReceiveAddressRoute(t *testing.T) {
 		t.Run(tc.desc, func(t *testing.T) {
 			t.Parallel()
 			w := httptest.NewRecorder()
-			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/receiveaddress/"+tc.planetmintAddress, bytes.NewBuffer([]byte{}))
+			query := url.Values{}
+			if tc.refundAddress != "" {
+				query.Set("refundaddress", tc.refundAddress)
+			}
+			if tc.ttl != "" {
+				query.Set("ttl", tc.ttl)
+			}
+			target := "/receiveaddress/" + tc.planetmintAddress + "?" + query.Encode()
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, target, bytes.NewBuffer([]byte{}))
 			router.ServeHTTP(w, req)
 			assert.Equal(t, tc.code, w.Code)
 			if w.Code != 200 {
@@ -81,6 +137,14 @@ func TestGetReceiveAddressRoute(t *testing.T) {
 				var result types.ReceiveAddressResponse
 				err = json.Unmarshal(w.Body.Bytes(), &result)
 				assert.NoError(t, err)
+				expiresIn := tc.expiresIn
+				if expiresIn == 0 {
+					expiresIn = config.GetConfig().AddressTTL
+				}
+				assert.InDelta(t, time.Now().Add(expiresIn).Unix(), result.ExpiresAt, 5)
+				result.ExpiresAt = 0
+				assert.Len(t, result.ConversionID, 16)
+				result.ConversionID = ""
 				assert.Equal(t, tc.resBody, result)
 			}
 		})
@@ -107,3 +171,160 @@ func TestPlmntAddress(t *testing.T) {
 		}
 	}
 }
+
+func TestGetConversionRoute(t *testing.T) {
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	pmClientMock := testutil.NewMockIPlanetmintClient(ctrl)
+	eClientMock := testutil.NewMockIElementsClient(ctrl)
+
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		db.Close()
+		stdlog.Fatal(err)
+	}
+	defer db.Close()
+	_ = service.NewR2PService(router, pmClientMock, eClientMock, service.NewStaticRateProvider(100), db, log.GetLogger(log.DEBUG))
+
+	eClientMock.EXPECT().GetNewAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ConfidentialAddr, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/receiveaddress/"+testutil.PlanetmintAddress, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, "/conversion/"+testutil.ConfidentialAddr, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res types.ConversionResponse
+	err = json.Unmarshal(w.Body.Bytes(), &res)
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.ConfidentialAddr, res.LiquidAddress)
+	assert.Equal(t, testutil.PlanetmintAddress, res.PlanetmintBeneficiary)
+	assert.Equal(t, types.StateRegistered, res.State)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, "/conversion/"+testutil.UnconfidentialAddr, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetArchivedConversionsRoute(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.AdminToken = "secret"
+	defer func() { cfg.AdminToken = "" }()
+
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	pmClientMock := testutil.NewMockIPlanetmintClient(ctrl)
+	eClientMock := testutil.NewMockIElementsClient(ctrl)
+
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		db.Close()
+		stdlog.Fatal(err)
+	}
+	defer db.Close()
+	r2p := service.NewR2PService(router, pmClientMock, eClientMock, service.NewStaticRateProvider(100), db, log.GetLogger(log.DEBUG))
+
+	deposit := testutil.Deposit1Of1Tx
+	deposit.Confirmations = cfg.Confirmations
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil)
+	eClientMock.EXPECT().GetTransaction(gomock.Any(), gomock.Any(), gomock.Any()).Return(deposit, nil)
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), gomock.Any()).Return(&daotypes.QueryGetMintRequestsByHashResponse{}, nil)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+	err = r2p.ExecutePotentialConversion(context.Background(), conversion)
+	assert.NoError(t, err)
+	r2p.CleanupDB()
+
+	tests := []struct {
+		desc  string
+		query string
+		code  int
+		count int
+	}{
+		{desc: "whole archive", query: "", code: http.StatusOK, count: 1},
+		{desc: "matching range", query: "?from=0&to=" + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10), code: http.StatusOK, count: 1},
+		{desc: "range in the past", query: "?from=0&to=1", code: http.StatusOK, count: 0},
+		{desc: "invalid timestamp", query: "?from=yesterday", code: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/admin/archive"+tc.query, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.code, w.Code)
+			if w.Code != http.StatusOK {
+				return
+			}
+			var res []types.ConversionResponse
+			err := json.Unmarshal(w.Body.Bytes(), &res)
+			assert.NoError(t, err)
+			assert.Len(t, res, tc.count)
+		})
+	}
+
+	// the archive lists beneficiaries and refund addresses, so it needs the admin token
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/admin/archive", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// archived conversions are still visible through the status endpoint
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, "/conversion/"+testutil.ConfidentialAddr, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestApproveConversionRoute(t *testing.T) {
+	cfg := config.GetConfig()
+
+	router := gin.Default()
+	ctrl := gomock.NewController(t)
+	pmClientMock := testutil.NewMockIPlanetmintClient(ctrl)
+	eClientMock := testutil.NewMockIElementsClient(ctrl)
+
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		db.Close()
+		stdlog.Fatal(err)
+	}
+	defer db.Close()
+	_ = service.NewR2PService(router, pmClientMock, eClientMock, service.NewStaticRateProvider(100), db, log.GetLogger(log.DEBUG))
+
+	eClientMock.EXPECT().GetNewAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ConfidentialAddr, nil)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/receiveaddress/"+testutil.PlanetmintAddress, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	tests := []struct {
+		desc          string
+		adminToken    string
+		authorization string
+		address       string
+		code          int
+	}{
+		{desc: "admin endpoints disabled", adminToken: "", authorization: "Bearer ", address: testutil.ConfidentialAddr, code: http.StatusForbidden},
+		{desc: "invalid token", adminToken: "secret", authorization: "Bearer guess", address: testutil.ConfidentialAddr, code: http.StatusUnauthorized},
+		{desc: "unknown address", adminToken: "secret", authorization: "Bearer secret", address: testutil.UnconfidentialAddr, code: http.StatusNotFound},
+		{desc: "nothing to approve", adminToken: "secret", authorization: "Bearer secret", address: testutil.ConfidentialAddr, code: http.StatusConflict},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			cfg.AdminToken = tc.adminToken
+			defer func() { cfg.AdminToken = "" }()
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/admin/conversion/"+tc.address+"/approve", nil)
+			req.Header.Set("Authorization", tc.authorization)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+}
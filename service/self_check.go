@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+)
+
+// CheckResult is the outcome of a single startup check, Err is nil if the check passed.
+type CheckResult struct {
+	Name string
+	Err  error
+}
+
+func (r CheckResult) String() string {
+	if r.Err != nil {
+		return "[failed] " + r.Name + ": " + r.Err.Error()
+	}
+	return "[ok] " + r.Name
+}
+
+// SelfCheck verifies the configuration against Elements and Planetmint before the service starts, so that
+// a misconfiguration is found at startup instead of at the first mint. All checks are run and reported,
+// err is set if any of them failed.
+func (r2p *R2PService) SelfCheck(ctx context.Context) (results []CheckResult, err error) {
+	cfg := config.GetConfig()
+	results = []CheckResult{
+		{Name: "elements wallet " + cfg.Wallet + " is loaded", Err: r2p.checkWallet(ctx)},
+		{Name: "accepted asset " + cfg.AcceptedAsset + " is known to elements", Err: r2p.checkAcceptedAsset(ctx)},
+		{Name: "planetmint is reachable on chain " + cfg.PlanetmintChainID, Err: r2p.checkChainID(ctx)},
+		{Name: "planetmint-address " + cfg.PlanetmintAddress + " is the mint address", Err: r2p.checkMintAddress(ctx)},
+	}
+	// simulated mints are not signed
+	if !cfg.DryRun {
+		results = append(results, CheckResult{
+			Name: "keyring holds the signing key of " + cfg.PlanetmintAddress,
+			Err:  r2p.pmClient.CheckSigningKey(cfg.PlanetmintAddress),
+		})
+	}
+
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Name, result.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func (r2p *R2PService) checkWallet(ctx context.Context) (err error) {
+	cfg := config.GetConfig()
+	loaded, err := r2p.eClient.ListWallets(ctx, cfg.GetElementsURL(), []string{})
+	if err != nil {
+		return
+	}
+	if slices.Contains(loaded, cfg.Wallet) {
+		return
+	}
+	available, err := r2p.eClient.ListWalletDir(ctx, cfg.GetElementsURL(), []string{})
+	if err != nil {
+		return
+	}
+	if slices.Contains(available, cfg.Wallet) {
+		return errors.New("the wallet exists but is not loaded")
+	}
+	return errors.New("the wallet does not exist")
+}
+
+// checkAcceptedAsset accepts assets that are labeled on the node or that the wallet has received before.
+func (r2p *R2PService) checkAcceptedAsset(ctx context.Context) (err error) {
+	cfg := config.GetConfig()
+	labels, err := r2p.eClient.DumpAssetLabels(ctx, cfg.GetElementsURL(), []string{})
+	if err != nil {
+		return
+	}
+	for _, asset := range labels {
+		if asset == cfg.AcceptedAsset {
+			return
+		}
+	}
+	balance, err := r2p.eClient.GetBalance(ctx, cfg.GetElementsURL(), []string{})
+	if err != nil {
+		return
+	}
+	if _, ok := balance[cfg.AcceptedAsset]; ok {
+		return
+	}
+	return errors.New("the asset is neither labeled on the node nor held by the wallet")
+}
+
+func (r2p *R2PService) checkChainID(ctx context.Context) (err error) {
+	cfg := config.GetConfig()
+	if err = r2p.pmClient.Health(ctx); err != nil {
+		return
+	}
+	chainID, err := r2p.pmClient.GetChainID(ctx)
+	if err != nil {
+		return
+	}
+	if chainID != cfg.PlanetmintChainID {
+		return fmt.Errorf("the node is on chain %s", chainID)
+	}
+	return
+}
+
+func (r2p *R2PService) checkMintAddress(ctx context.Context) (err error) {
+	cfg := config.GetConfig()
+	mintAddress, err := r2p.pmClient.GetMintAddress(ctx)
+	if err != nil {
+		return
+	}
+	if mintAddress != cfg.PlanetmintAddress {
+		return fmt.Errorf("the chain's mint address is %s", mintAddress)
+	}
+	return
+}
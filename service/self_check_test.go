@@ -0,0 +1,77 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	elementstypes "github.com/rddl-network/elements-rpc/types"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelfCheck(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, _, pmClientMock, eClientMock := setupR2PService(t)
+
+	eClientMock.EXPECT().ListWallets(gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{cfg.Wallet}, nil)
+	eClientMock.EXPECT().DumpAssetLabels(gomock.Any(), gomock.Any(), gomock.Any()).Return(map[string]string{"RDDL": cfg.AcceptedAsset}, nil)
+	pmClientMock.EXPECT().Health(gomock.Any()).Return(nil)
+	pmClientMock.EXPECT().GetChainID(gomock.Any()).Return(cfg.PlanetmintChainID, nil)
+	pmClientMock.EXPECT().GetMintAddress(gomock.Any()).Return(cfg.PlanetmintAddress, nil)
+	pmClientMock.EXPECT().CheckSigningKey(cfg.PlanetmintAddress).Return(nil)
+
+	results, err := r2p.SelfCheck(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, results, 5)
+	for _, result := range results {
+		assert.NoError(t, result.Err)
+		assert.Contains(t, result.String(), "[ok] ")
+	}
+}
+
+func TestSelfCheckFailures(t *testing.T) {
+	cfg := config.GetConfig()
+	r2p, _, pmClientMock, eClientMock := setupR2PService(t)
+
+	eClientMock.EXPECT().ListWallets(gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{"other"}, nil)
+	eClientMock.EXPECT().ListWalletDir(gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{"other", cfg.Wallet}, nil)
+	eClientMock.EXPECT().DumpAssetLabels(gomock.Any(), gomock.Any(), gomock.Any()).Return(map[string]string{}, nil)
+	eClientMock.EXPECT().GetBalance(gomock.Any(), gomock.Any(), gomock.Any()).Return(elementstypes.GetBalanceResult{cfg.AcceptedAsset: 1}, nil)
+	pmClientMock.EXPECT().Health(gomock.Any()).Return(nil)
+	pmClientMock.EXPECT().GetChainID(gomock.Any()).Return("planetmint-mainnet-1", nil)
+	pmClientMock.EXPECT().GetMintAddress(gomock.Any()).Return(testutil.PlanetmintAddress, nil)
+	pmClientMock.EXPECT().CheckSigningKey(cfg.PlanetmintAddress).Return(errors.New("key not found"))
+
+	results, err := r2p.SelfCheck(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, results, 5)
+	assert.EqualError(t, results[0].Err, "the wallet exists but is not loaded")
+	assert.NoError(t, results[1].Err)
+	assert.EqualError(t, results[2].Err, "the node is on chain planetmint-mainnet-1")
+	assert.EqualError(t, results[3].Err, "the chain's mint address is "+testutil.PlanetmintAddress)
+	assert.EqualError(t, results[4].Err, "key not found")
+	assert.Equal(t, "[failed] keyring holds the signing key of "+cfg.PlanetmintAddress+": key not found", results[4].String())
+}
+
+func TestSelfCheckDryRun(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.DryRun = true
+	defer func() { cfg.DryRun = false }()
+	r2p, _, pmClientMock, eClientMock := setupR2PService(t)
+
+	eClientMock.EXPECT().ListWallets(gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{}, nil)
+	eClientMock.EXPECT().ListWalletDir(gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{}, nil)
+	eClientMock.EXPECT().DumpAssetLabels(gomock.Any(), gomock.Any(), gomock.Any()).Return(map[string]string{"RDDL": cfg.AcceptedAsset}, nil)
+	pmClientMock.EXPECT().Health(gomock.Any()).Return(errors.New("connection refused"))
+	pmClientMock.EXPECT().GetMintAddress(gomock.Any()).Return(cfg.PlanetmintAddress, nil)
+
+	// the signing key is not checked, simulated mints are not signed
+	results, err := r2p.SelfCheck(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, results, 4)
+	assert.EqualError(t, results[0].Err, "the wallet does not exist")
+	assert.EqualError(t, results[2].Err, "connection refused")
+}
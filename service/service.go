@@ -1,48 +1,139 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	log "github.com/rddl-network/go-utils/logger"
+	"github.com/planetmint/planetmint-go/util"
 	"github.com/spf13/viper"
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
 type R2PService struct {
-	router     *gin.Engine
-	pmClient   IPlanetmintClient
-	eClient    IElementsClient
-	db         *leveldb.DB
-	dbMutex    sync.Mutex // Mutex to synchronize write operations
-	tickerList []*time.Ticker
-	logger     log.AppLogger
+	router               *gin.Engine
+	pmClient             IPlanetmintClient
+	eClient              IElementsClient
+	rateProvider         RateProvider
+	db                   *leveldb.DB
+	dbMutex              sync.Mutex // Mutex to synchronize write operations
+	tickerList           []*time.Ticker
+	logger               Logger
+	done                 <-chan struct{}    // closed on shutdown to stop the periodic tasks and their passes
+	cancel               context.CancelFunc // closes done
+	workers              sync.WaitGroup     // periodic task loop and the passes it started
+	serverMutex          sync.Mutex         // Mutex to synchronize starting the HTTP server with shutdown
+	server               *http.Server
+	cleanupPass          *pass
+	conversionPass       *pass
+	reconciliationPass   *pass
+	latestReconciliation latestReconciliation
+	entryLocks           entryLocks // synchronize the work on a conversion request
+	txLocks              entryLocks // synchronize minting per Liquid transaction
+	ledgerLocks          entryLocks // synchronize remainder ledger updates with minting per beneficiary
 }
 
-func NewR2PService(router *gin.Engine, pmClient IPlanetmintClient, eClient IElementsClient, db *leveldb.DB, logger log.AppLogger) *R2PService {
-	service := &R2PService{router: router, pmClient: pmClient, eClient: eClient, db: db, logger: logger}
+func NewR2PService(router *gin.Engine, pmClient IPlanetmintClient, eClient IElementsClient, rateProvider RateProvider, db *leveldb.DB, logger Logger) *R2PService {
+	service := &R2PService{router: router, pmClient: pmClient, eClient: eClient, rateProvider: rateProvider, db: db, logger: logger}
+	ctx, cancel := context.WithCancel(context.Background())
+	service.done, service.cancel = ctx.Done(), cancel
+	service.cleanupPass = newPass("cleanup", service.cleanupDB)
+	service.conversionPass = newPass("conversion", service.convertArrivedFunds)
+	service.reconciliationPass = newPass("reconciliation", service.reconcile)
 	gin.SetMode(gin.ReleaseMode)
 	service.configureRouter()
 	service.registerRoutes()
-	service.registerPeriodicTasks()
 	return service
 }
 
+// Run starts the periodic tasks and serves the HTTP API until the service is shut down. The periodic tasks
+// are not started by NewR2PService, so that the self-check runs before any conversion.
 func (r2p *R2PService) Run(config *viper.Viper) (err error) {
 	serviceBind := config.GetString("service-bind")
 	servicePort := config.GetString("service-port")
-	return r2p.router.Run(fmt.Sprintf("%s:%s", serviceBind, servicePort))
+
+	r2p.serverMutex.Lock()
+	if r2p.isShutDown() {
+		r2p.serverMutex.Unlock()
+		return
+	}
+	r2p.registerPeriodicTasks()
+	r2p.server = &http.Server{
+		Addr:              fmt.Sprintf("%s:%s", serviceBind, servicePort),
+		Handler:           r2p.router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	server := r2p.server
+	r2p.serverMutex.Unlock()
+
+	err = server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	return
 }
 
-// TODO: Constant rate to be replaced with conversion rate monitor
+// isShutDown reports whether Shutdown was called.
+func (r2p *R2PService) isShutDown() bool {
+	select {
+	case <-r2p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown stops accepting requests, cancels the running conversion passes, stops the periodic tasks
+// and closes the database once all workers returned. Open requests and workers are waited for until
+// the context is done.
+func (r2p *R2PService) Shutdown(ctx context.Context) (err error) {
+	var errs []error
+
+	r2p.serverMutex.Lock()
+	r2p.cancel()
+	server := r2p.server
+	r2p.serverMutex.Unlock()
+	if server != nil {
+		errs = append(errs, server.Shutdown(ctx))
+	}
+
+	for _, ticker := range r2p.tickerList {
+		ticker.Stop()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		r2p.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// closing the database under running workers would fail their writes
+		errs = append(errs, fmt.Errorf("waiting for periodic tasks: %w", ctx.Err()))
+		return errors.Join(errs...)
+	}
+
+	errs = append(errs, r2p.db.Close())
+	return errors.Join(errs...)
+}
+
+// GetConversion applies the conversion rate (PLMNT per RDDL) to the RDDL amount.
 // Cut away the PLMNT fractions as planetmint only works with natural numbers
-func GetConversion(rddl uint64) (plmnt uint64) {
-	conversionRate := uint64(100) // apply conversion rate
-	plmntAmount := rddl * conversionRate
-	// TODO: 100000000 to be replaced by util.Factor
-	plmnt = plmntAmount / 100000000
+func GetConversion(rddl uint64, conversionRate uint64) (plmnt uint64) {
+	plmnt, _ = GetConversionWithRemainder(rddl, conversionRate, 0)
+	return
+}
 
+// GetConversionWithRemainder applies the conversion rate to the RDDL amount and adds the credit (in 1e-8 PLMNT)
+// carried over from earlier conversions. The cut away PLMNT fraction is returned as remainder (in 1e-8 PLMNT).
+func GetConversionWithRemainder(rddl uint64, conversionRate uint64, credit uint64) (plmnt uint64, remainder uint64) {
+	plmntAmount := rddl*conversionRate + credit
+	plmnt = plmntAmount / uint64(util.Factor)
+	remainder = plmntAmount % uint64(util.Factor)
 	return
 }
@@ -0,0 +1,104 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	elementstypes "github.com/rddl-network/elements-rpc/types"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/service"
+	"github.com/rddl-network/rddl-2-plmnt-service/testutil"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestShutdown(t *testing.T) {
+	cfg := config.GetConfig()
+	cfg.CleanupInterval = 10 * time.Millisecond
+	cfg.ConversionInterval = 10 * time.Millisecond
+	defer func() {
+		defaults := config.DefaultConfig()
+		cfg.CleanupInterval = defaults.CleanupInterval
+		cfg.ConversionInterval = defaults.ConversionInterval
+	}()
+	r2p, _, _, _ := setupR2PService(t)
+
+	// the periodic tasks wait for Run
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, r2p.GetPassStatus()["conversion"].Passes)
+
+	v := viper.New()
+	v.Set("service-bind", "127.0.0.1")
+	v.Set("service-port", "0")
+	done := make(chan error)
+	go func() { done <- r2p.Run(v) }()
+
+	// let the periodic tasks run a few passes
+	assert.Eventually(t, func() bool { return r2p.GetPassStatus()["conversion"].Passes > 0 }, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, r2p.Shutdown(ctx))
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after Shutdown")
+	}
+
+	// the database is closed and no further passes are started
+	_, err := r2p.GetConversionRequest("address")
+	assert.ErrorIs(t, err, leveldb.ErrClosed)
+	assert.NoError(t, r2p.Run(v))
+}
+
+func TestSingleFlightConversionPass(t *testing.T) {
+	r2p, router, _, eClientMock := setupR2PService(t)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+	conversion.Timestamp = time.Now().Unix()
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+	assert.NoError(t, r2p.ExecutePotentialConversion(context.Background(), conversion))
+
+	// block the first pass in the elements RPC call
+	started := make(chan struct{})
+	release := make(chan struct{})
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, _ []string) ([]elementstypes.ListReceivedByAddressResult, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	r2p.StartConversionPass()
+	<-started
+
+	// ticks arriving while the pass runs are skipped
+	r2p.StartConversionPass()
+	r2p.StartConversionPass()
+	status := r2p.GetPassStatus()["conversion"]
+	assert.True(t, status.Running)
+	assert.Equal(t, uint64(2), status.SkippedTicks)
+	assert.Equal(t, uint64(0), status.Passes)
+
+	close(release)
+	assert.Eventually(t, func() bool { return !r2p.GetPassStatus()["conversion"].Running }, time.Second, 10*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/passes", nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var passes map[string]types.PassStatus
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &passes))
+	assert.Equal(t, uint64(1), passes["conversion"].Passes)
+	assert.Equal(t, uint64(2), passes["conversion"].SkippedTicks)
+	assert.Equal(t, uint64(0), passes["cleanup"].Passes)
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serviceName = "rddl-2-plmnt-service"
+
+// tracer creates the spans of the service. It uses the global tracer provider, so spans are dropped unless
+// tracing is set up with SetupTracing.
+var tracer = otel.Tracer("github.com/rddl-network/rddl-2-plmnt-service/service")
+
+// Span attributes identifying the conversion and the deposit a span works on.
+const (
+	attrLiquidAddress    = attribute.Key("liquid.address")
+	attrLiquidTxID       = attribute.Key("liquid.txid")
+	attrBeneficiary      = attribute.Key("planetmint.beneficiary")
+	attrPlanetmintTxHash = attribute.Key("planetmint.txhash")
+	attrPLMNTAmount      = attribute.Key("planetmint.amount")
+)
+
+// SetupTracing installs the global tracer provider that sends the spans to the configured trace-exporter
+// and returns the function flushing and stopping it. Spans are dropped with trace-exporter none.
+func SetupTracing(ctx context.Context, cfg *config.Config) (shutdown func(context.Context) error, err error) {
+	shutdown = func(context.Context) error { return nil }
+	var exporter sdktrace.SpanExporter
+	if cfg.TraceExporter == config.TraceExporterStdout {
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	}
+	if cfg.TraceExporter == config.TraceExporterOTLP {
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint)}
+		if !cfg.OTLPTLS {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	}
+	if err != nil {
+		return shutdown, fmt.Errorf("creating %s trace exporter: %w", cfg.TraceExporter, err)
+	}
+	if exporter == nil {
+		return
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// endSpan records the error with the span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,101 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/rddl-network/rddl-2-plmnt-service/config"
+	"github.com/rddl-network/rddl-2-plmnt-service/service"
+	"github.com/rddl-network/rddl-2-plmnt-service/testutil"
+	"github.com/rddl-network/rddl-2-plmnt-service/types"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	spanExporter     *tracetest.InMemoryExporter
+	setupSpanRecords sync.Once
+)
+
+// recordSpans installs a tracer provider that keeps the spans in memory, like SetupTracing does with an
+// exporter. The global tracer provider can only be installed once, so the exporter is shared and reset
+// by every test.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	setupSpanRecords.Do(func() {
+		spanExporter = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spanExporter)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	spanExporter.Reset()
+	return spanExporter
+}
+
+func TestConversionSpans(t *testing.T) {
+	cfg := config.GetConfig()
+	exporter := recordSpans(t)
+	r2p, _, pmClientMock, eClientMock := setupR2PService(t)
+
+	var conversion service.ConversionRequest
+	conversion.ConfidentialAddress = testutil.ConfidentialAddr
+	conversion.PlanetmintAddress = testutil.PlanetmintAddress
+	eClientMock.EXPECT().ListReceivedByAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ReceivedTxByAddressArray1Tx, nil)
+	expectGetTransaction(eClientMock, confirmed(testutil.Deposit1Of1Tx, cfg.Confirmations))
+	pmClientMock.EXPECT().CheckMintRequest(gomock.Any(), gomock.Any()).Return(nil, nil)
+	pmClientMock.EXPECT().MintPLMNT(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(types.TxResult{TxHash: "HASH", Code: 5, Log: "unauthorized"}, nil)
+	assert.Error(t, r2p.ExecutePotentialConversion(context.Background(), conversion))
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	assert.Len(t, spans, 4)
+	root := spans["ExecutePotentialConversion"]
+	assert.Contains(t, root.Attributes, attribute.String("liquid.address", testutil.ConfidentialAddr))
+	assert.Equal(t, codes.Error, root.Status.Code)
+
+	list := spans["ListReceivedByAddress"]
+	assert.Equal(t, root.SpanContext.SpanID(), list.Parent.SpanID())
+	assert.Contains(t, list.Attributes, attribute.String("liquid.address", testutil.ConfidentialAddr))
+
+	check := spans["CheckMintRequest"]
+	assert.Equal(t, root.SpanContext.SpanID(), check.Parent.SpanID())
+	assert.Contains(t, check.Attributes, attribute.String("liquid.txid", testutil.Deposit1Of1Tx.TxID))
+
+	mint := spans["MintPLMNT"]
+	assert.Equal(t, root.SpanContext.SpanID(), mint.Parent.SpanID())
+	assert.Contains(t, mint.Attributes, attribute.String("liquid.txid", testutil.Deposit1Of1Tx.TxID))
+	assert.Contains(t, mint.Attributes, attribute.String("planetmint.txhash", "HASH"))
+	assert.Equal(t, codes.Error, mint.Status.Code)
+}
+
+func TestReceiveAddressSpan(t *testing.T) {
+	exporter := recordSpans(t)
+	_, router, _, eClientMock := setupR2PService(t)
+
+	eClientMock.EXPECT().GetNewAddress(gomock.Any(), gomock.Any(), gomock.Any()).Return(testutil.ConfidentialAddr, nil)
+	w := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/receiveaddress/"+testutil.PlanetmintAddress, nil)
+	assert.NoError(t, err)
+	// the span joins the trace of the caller
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET /receiveaddress", spans[0].Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+	assert.Contains(t, spans[0].Attributes, attribute.String("planetmint.beneficiary", testutil.PlanetmintAddress))
+	assert.Contains(t, spans[0].Attributes, attribute.String("liquid.address", testutil.ConfidentialAddr))
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+}
@@ -5,10 +5,12 @@
 package testutil
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
 	types "github.com/rddl-network/elements-rpc/types"
+	types0 "github.com/rddl-network/rddl-2-plmnt-service/types"
 )
 
 // MockIElementsClient is a mock of IElementsClient interface.
@@ -34,32 +36,137 @@ func (m *MockIElementsClient) EXPECT() *MockIElementsClientMockRecorder {
 	return m.recorder
 }
 
+// DumpAssetLabels mocks base method.
+func (m *MockIElementsClient) DumpAssetLabels(ctx context.Context, url string, params []string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DumpAssetLabels", ctx, url, params)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DumpAssetLabels indicates an expected call of DumpAssetLabels.
+func (mr *MockIElementsClientMockRecorder) DumpAssetLabels(ctx, url, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DumpAssetLabels", reflect.TypeOf((*MockIElementsClient)(nil).DumpAssetLabels), ctx, url, params)
+}
+
+// GetBalance mocks base method.
+func (m *MockIElementsClient) GetBalance(ctx context.Context, url string, params []string) (types.GetBalanceResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalance", ctx, url, params)
+	ret0, _ := ret[0].(types.GetBalanceResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalance indicates an expected call of GetBalance.
+func (mr *MockIElementsClientMockRecorder) GetBalance(ctx, url, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockIElementsClient)(nil).GetBalance), ctx, url, params)
+}
+
 // GetNewAddress mocks base method.
-func (m *MockIElementsClient) GetNewAddress(url string, params []string) (string, error) {
+func (m *MockIElementsClient) GetNewAddress(ctx context.Context, url string, params []string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetNewAddress", url, params)
+	ret := m.ctrl.Call(m, "GetNewAddress", ctx, url, params)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetNewAddress indicates an expected call of GetNewAddress.
-func (mr *MockIElementsClientMockRecorder) GetNewAddress(url, params interface{}) *gomock.Call {
+func (mr *MockIElementsClientMockRecorder) GetNewAddress(ctx, url, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNewAddress", reflect.TypeOf((*MockIElementsClient)(nil).GetNewAddress), ctx, url, params)
+}
+
+// GetTransaction mocks base method.
+func (m *MockIElementsClient) GetTransaction(ctx context.Context, url string, params []string) (types.GetTransactionResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransaction", ctx, url, params)
+	ret0, _ := ret[0].(types.GetTransactionResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransaction indicates an expected call of GetTransaction.
+func (mr *MockIElementsClientMockRecorder) GetTransaction(ctx, url, params interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNewAddress", reflect.TypeOf((*MockIElementsClient)(nil).GetNewAddress), url, params)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransaction", reflect.TypeOf((*MockIElementsClient)(nil).GetTransaction), ctx, url, params)
 }
 
 // ListReceivedByAddress mocks base method.
-func (m *MockIElementsClient) ListReceivedByAddress(url string, params []string) ([]types.ListReceivedByAddressResult, error) {
+func (m *MockIElementsClient) ListReceivedByAddress(ctx context.Context, url string, params []string) ([]types.ListReceivedByAddressResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListReceivedByAddress", url, params)
+	ret := m.ctrl.Call(m, "ListReceivedByAddress", ctx, url, params)
 	ret0, _ := ret[0].([]types.ListReceivedByAddressResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ListReceivedByAddress indicates an expected call of ListReceivedByAddress.
-func (mr *MockIElementsClientMockRecorder) ListReceivedByAddress(url, params interface{}) *gomock.Call {
+func (mr *MockIElementsClientMockRecorder) ListReceivedByAddress(ctx, url, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReceivedByAddress", reflect.TypeOf((*MockIElementsClient)(nil).ListReceivedByAddress), ctx, url, params)
+}
+
+// ListTransactions mocks base method.
+func (m *MockIElementsClient) ListTransactions(ctx context.Context, url string, params []string) ([]types0.WalletTransaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransactions", ctx, url, params)
+	ret0, _ := ret[0].([]types0.WalletTransaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransactions indicates an expected call of ListTransactions.
+func (mr *MockIElementsClientMockRecorder) ListTransactions(ctx, url, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransactions", reflect.TypeOf((*MockIElementsClient)(nil).ListTransactions), ctx, url, params)
+}
+
+// ListWalletDir mocks base method.
+func (m *MockIElementsClient) ListWalletDir(ctx context.Context, url string, params []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWalletDir", ctx, url, params)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWalletDir indicates an expected call of ListWalletDir.
+func (mr *MockIElementsClientMockRecorder) ListWalletDir(ctx, url, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWalletDir", reflect.TypeOf((*MockIElementsClient)(nil).ListWalletDir), ctx, url, params)
+}
+
+// ListWallets mocks base method.
+func (m *MockIElementsClient) ListWallets(ctx context.Context, url string, params []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWallets", ctx, url, params)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWallets indicates an expected call of ListWallets.
+func (mr *MockIElementsClientMockRecorder) ListWallets(ctx, url, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWallets", reflect.TypeOf((*MockIElementsClient)(nil).ListWallets), ctx, url, params)
+}
+
+// SendToAddress mocks base method.
+func (m *MockIElementsClient) SendToAddress(ctx context.Context, url string, params []string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendToAddress", ctx, url, params)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendToAddress indicates an expected call of SendToAddress.
+func (mr *MockIElementsClientMockRecorder) SendToAddress(ctx, url, params interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReceivedByAddress", reflect.TypeOf((*MockIElementsClient)(nil).ListReceivedByAddress), url, params)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendToAddress", reflect.TypeOf((*MockIElementsClient)(nil).SendToAddress), ctx, url, params)
 }
@@ -2,6 +2,7 @@ package testutil
 
 import (
 	"github.com/rddl-network/elements-rpc/types"
+	r2ptypes "github.com/rddl-network/rddl-2-plmnt-service/types"
 )
 
 var (
@@ -20,4 +21,9 @@ var (
 	ReceivedTxByAddress2Tx      = types.ListReceivedByAddressResult{Address: ConfidentialAddr, Amount: 2.00000000, Confirmations: 2, TxIDs: []string{"44e7812ffa95a4031c1b97f534c2535fdad583627203bf63db8d5909902b6a87", "87d8be31018183c7b6e013ef712d186a3e7aca08b37abe6bc86acda23692cb9b"}}
 	ReceivedTxByAddressArray2Tx = []types.ListReceivedByAddressResult{ReceivedTxByAddress2Tx}
 	ReceivedTxByAddressArray1Tx = []types.ListReceivedByAddressResult{ReceivedTxByAddress1Tx}
+	AcceptedAsset               = "7add40beb27df701e02ee85089c5bc0021bc813823fedb5f1dcb5debda7f3da9"
+	Deposit1Of1Tx               = types.GetTransactionResult{TxID: ReceivedTxByAddress1Tx.TxIDs[0], Amount: map[string]float64{AcceptedAsset: 2.00000000}, Confirmations: 2, Details: []types.GetTransactionDetailsResult{{Address: ConfidentialAddr, Amount: 2.00000000, Category: "receive"}}}
+	Deposit1Of2Tx               = types.GetTransactionResult{TxID: ReceivedTxByAddress2Tx.TxIDs[0], Amount: map[string]float64{AcceptedAsset: 1.50000000}, Confirmations: 2, Details: []types.GetTransactionDetailsResult{{Address: ConfidentialAddr, Amount: 1.50000000, Category: "receive"}}}
+	Deposit2Of2Tx               = types.GetTransactionResult{TxID: ReceivedTxByAddress2Tx.TxIDs[1], Amount: map[string]float64{AcceptedAsset: 0.50000000}, Confirmations: 2, Details: []types.GetTransactionDetailsResult{{Address: ConfidentialAddr, Amount: 0.50000000, Category: "receive"}}}
+	MintTxResult                = r2ptypes.TxResult{TxHash: "D1C5E2F0A5B7C3E4F6A8B9C0D1E2F3A4B5C6D7E8F9A0B1C2D3E4F5A6B7C8D9E0"}
 )
@@ -5,10 +5,12 @@
 package testutil
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
 	types "github.com/planetmint/planetmint-go/x/dao/types"
+	types0 "github.com/rddl-network/rddl-2-plmnt-service/types"
 )
 
 // MockIPlanetmintClient is a mock of IPlanetmintClient interface.
@@ -35,30 +37,120 @@ func (m *MockIPlanetmintClient) EXPECT() *MockIPlanetmintClientMockRecorder {
 }
 
 // CheckMintRequest mocks base method.
-func (m *MockIPlanetmintClient) CheckMintRequest(txhash string) (*types.QueryGetMintRequestsByHashResponse, error) {
+func (m *MockIPlanetmintClient) CheckMintRequest(ctx context.Context, txhash string) (*types.QueryGetMintRequestsByHashResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CheckMintRequest", txhash)
+	ret := m.ctrl.Call(m, "CheckMintRequest", ctx, txhash)
 	ret0, _ := ret[0].(*types.QueryGetMintRequestsByHashResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CheckMintRequest indicates an expected call of CheckMintRequest.
-func (mr *MockIPlanetmintClientMockRecorder) CheckMintRequest(txhash interface{}) *gomock.Call {
+func (mr *MockIPlanetmintClientMockRecorder) CheckMintRequest(ctx, txhash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckMintRequest", reflect.TypeOf((*MockIPlanetmintClient)(nil).CheckMintRequest), txhash)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckMintRequest", reflect.TypeOf((*MockIPlanetmintClient)(nil).CheckMintRequest), ctx, txhash)
 }
 
-// MintPLMNT mocks base method.
-func (m *MockIPlanetmintClient) MintPLMNT(beneficiary string, amount uint64, liquidTxHash string) error {
+// CheckSigningKey mocks base method.
+func (m *MockIPlanetmintClient) CheckSigningKey(address string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckSigningKey", address)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckSigningKey indicates an expected call of CheckSigningKey.
+func (mr *MockIPlanetmintClientMockRecorder) CheckSigningKey(address interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSigningKey", reflect.TypeOf((*MockIPlanetmintClient)(nil).CheckSigningKey), address)
+}
+
+// GetChainID mocks base method.
+func (m *MockIPlanetmintClient) GetChainID(ctx context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChainID", ctx)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChainID indicates an expected call of GetChainID.
+func (mr *MockIPlanetmintClientMockRecorder) GetChainID(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChainID", reflect.TypeOf((*MockIPlanetmintClient)(nil).GetChainID), ctx)
+}
+
+// GetMintAddress mocks base method.
+func (m *MockIPlanetmintClient) GetMintAddress(ctx context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMintAddress", ctx)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMintAddress indicates an expected call of GetMintAddress.
+func (mr *MockIPlanetmintClientMockRecorder) GetMintAddress(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMintAddress", reflect.TypeOf((*MockIPlanetmintClient)(nil).GetMintAddress), ctx)
+}
+
+// GetTxResult mocks base method.
+func (m *MockIPlanetmintClient) GetTxResult(ctx context.Context, txHash string) (types0.TxResult, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTxResult", ctx, txHash)
+	ret0, _ := ret[0].(types0.TxResult)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetTxResult indicates an expected call of GetTxResult.
+func (mr *MockIPlanetmintClientMockRecorder) GetTxResult(ctx, txHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTxResult", reflect.TypeOf((*MockIPlanetmintClient)(nil).GetTxResult), ctx, txHash)
+}
+
+// Health mocks base method.
+func (m *MockIPlanetmintClient) Health(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "MintPLMNT", beneficiary, amount, liquidTxHash)
+	ret := m.ctrl.Call(m, "Health", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// Health indicates an expected call of Health.
+func (mr *MockIPlanetmintClientMockRecorder) Health(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Health", reflect.TypeOf((*MockIPlanetmintClient)(nil).Health), ctx)
+}
+
+// MintPLMNT mocks base method.
+func (m *MockIPlanetmintClient) MintPLMNT(ctx context.Context, beneficiary string, amount uint64, liquidTxHash string) (types0.TxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MintPLMNT", ctx, beneficiary, amount, liquidTxHash)
+	ret0, _ := ret[0].(types0.TxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // MintPLMNT indicates an expected call of MintPLMNT.
-func (mr *MockIPlanetmintClientMockRecorder) MintPLMNT(beneficiary, amount, liquidTxHash interface{}) *gomock.Call {
+func (mr *MockIPlanetmintClientMockRecorder) MintPLMNT(ctx, beneficiary, amount, liquidTxHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MintPLMNT", reflect.TypeOf((*MockIPlanetmintClient)(nil).MintPLMNT), ctx, beneficiary, amount, liquidTxHash)
+}
+
+// MintPLMNTBatch mocks base method.
+func (m *MockIPlanetmintClient) MintPLMNTBatch(ctx context.Context, mintRequests []types.MintRequest) (types0.TxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MintPLMNTBatch", ctx, mintRequests)
+	ret0, _ := ret[0].(types0.TxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MintPLMNTBatch indicates an expected call of MintPLMNTBatch.
+func (mr *MockIPlanetmintClientMockRecorder) MintPLMNTBatch(ctx, mintRequests interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MintPLMNT", reflect.TypeOf((*MockIPlanetmintClient)(nil).MintPLMNT), beneficiary, amount, liquidTxHash)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MintPLMNTBatch", reflect.TypeOf((*MockIPlanetmintClient)(nil).MintPLMNTBatch), ctx, mintRequests)
 }
@@ -1,6 +1,202 @@
 package types
 
 type ReceiveAddressResponse struct {
+	ConversionID          string `json:"conversion-id"`
 	LiquidAddress         string `binding:"required" json:"liquid-address"`
 	PlanetmintBeneficiary string `binding:"required" json:"planetmint-beneficiary"`
+	RefundAddress         string `json:"refund-address"`
+	ExpiresAt             int64  `json:"expires-at"`
+}
+
+// ConversionState describes where a conversion request is in its lifecycle.
+type ConversionState string
+
+const (
+	StateRegistered    ConversionState = "registered"
+	StateFundsDetected ConversionState = "funds-detected"
+	StateConfirmed     ConversionState = "confirmed"
+	StateMintBroadcast ConversionState = "mint-broadcast"
+	StateMintConfirmed ConversionState = "mint-confirmed"
+	StateExpired       ConversionState = "expired"
+	StateFailed        ConversionState = "failed"
+	StateNeedsReview   ConversionState = "needs-review"
+	StateCredited      ConversionState = "credited"
+	StateDust          ConversionState = "dust"
+	StateRefunded      ConversionState = "refunded"
+	StateLateDeposit   ConversionState = "late-deposit"
+	StateSimulated     ConversionState = "simulated"
+)
+
+// StateTransition records when a conversion request entered a state.
+type StateTransition struct {
+	State     ConversionState `json:"state"`
+	Timestamp int64           `json:"timestamp"`
+}
+
+// ConversionRate is the number of PLMNT minted per RDDL at the time given by Timestamp.
+// It is also the format read by the file and HTTP backed rate providers.
+type ConversionRate struct {
+	Rate      uint64 `json:"rate"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Deposit is a single Liquid transaction received on a conversion's receive address.
+// Every deposit is minted individually.
+type Deposit struct {
+	LiquidTxID        string          `json:"liquid-tx-id"`
+	Confirmations     uint64          `json:"confirmations"`
+	RDDLAmount        uint64          `json:"rddl-amount"`
+	PLMNTAmount       uint64          `json:"plmnt-amount"`
+	ConversionRate    uint64          `json:"conversion-rate"`
+	RemainderCredit   uint64          `json:"remainder-credit"`
+	Remainder         uint64          `json:"remainder"`
+	RemainderReverted bool            `json:"remainder-reverted"` // the remainder was taken back from the ledger as the deposit was not minted
+	State             ConversionState `json:"state"`
+	Approved          bool            `json:"approved"`
+	MintAttempts      int             `json:"mint-attempts"`
+	MintSeparately    bool            `json:"mint-separately"` // a batched mint of the deposit failed in its block
+	PlanetmintTxHash  string          `json:"planetmint-tx-hash"`
+	PlanetmintTxCode  uint32          `json:"planetmint-tx-code"`
+	BroadcastAt       int64           `json:"broadcast-at"`
+	MintedAt          int64           `json:"minted-at"`
+	CompletedAt       int64           `json:"completed-at"` // the deposit reached its final state
+	SimulatedGas      uint64          `json:"simulated-gas"`
+	RefundTxID        string          `json:"refund-tx-id"`
+}
+
+// TxResult is the outcome of a Planetmint transaction. A non-zero Code means the transaction was
+// rejected or failed, Log holds the reason. Simulated transactions were not broadcast and have no hash.
+type TxResult struct {
+	TxHash    string `json:"tx-hash"`
+	Code      uint32 `json:"code"`
+	Log       string `json:"log"`
+	Simulated bool   `json:"simulated"`
+	GasUsed   uint64 `json:"gas-used"`
+}
+
+type ConversionResponse struct {
+	ID                    string            `json:"id"`
+	LiquidAddress         string            `json:"liquid-address"`
+	PlanetmintBeneficiary string            `json:"planetmint-beneficiary"`
+	RefundAddress         string            `json:"refund-address"`
+	Timestamp             int64             `json:"timestamp"`
+	ExpiresAt             int64             `json:"expires-at"`
+	State                 ConversionState   `json:"state"`
+	History               []StateTransition `json:"history"`
+	Deposits              []Deposit         `json:"deposits"`
+	LastError             string            `json:"last-error"`
+	UpdatedAt             int64             `json:"updated-at"`
+}
+
+// Remainder is the PLMNT fraction in 1e-8 PLMNT that was cut away from earlier conversions of the
+// beneficiary. It is credited on the beneficiary's next conversion.
+type Remainder struct {
+	Beneficiary string `json:"beneficiary"`
+	Remainder   uint64 `json:"remainder"`
+}
+
+// PassStatus describes the runs of a periodic task.
+type PassStatus struct {
+	Running        bool   `json:"running"`
+	Passes         uint64 `json:"passes"`
+	SkippedTicks   uint64 `json:"skipped-ticks"`
+	LastStart      int64  `json:"last-start"`
+	LastDurationMs int64  `json:"last-duration-ms"`
+	LastSuccess    int64  `json:"last-success"` // end of the last pass that got through all entries
+}
+
+// WalletTransaction is an entry of the Elements listtransactions RPC, one per output the wallet received or
+// sent. Amount is in whole units of Asset.
+type WalletTransaction struct {
+	Address  string  `json:"address"`
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+	Asset    string  `json:"asset"`
+	TxID     string  `json:"txid"`
+	Vout     uint32  `json:"vout"`
+	Time     int64   `json:"time"`
+}
+
+// DependencyStatus is the reachability of a dependency of the service, Error is set if it is down.
+type DependencyStatus struct {
+	Up    bool   `json:"up"`
+	Error string `json:"error,omitempty"`
+}
+
+// ReadinessResponse reports whether the service can serve requests. LastConversionPass is the end
+// of the last complete conversion pass, 0 if none completed yet.
+type ReadinessResponse struct {
+	Ready                      bool                        `json:"ready"`
+	Dependencies               map[string]DependencyStatus `json:"dependencies"`
+	LastConversionPass         int64                       `json:"last-conversion-pass"`
+	SecondsSinceConversionPass int64                       `json:"seconds-since-conversion-pass,omitempty"`
+}
+
+// ReconciliationIssueKind describes why a wallet receipt could not be reconciled with a mint.
+type ReconciliationIssueKind string
+
+const (
+	IssueUnmatchedReceipt    ReconciliationIssueKind = "unmatched-receipt"
+	IssueDoubleMint          ReconciliationIssueKind = "double-mint"
+	IssueAmountMismatch      ReconciliationIssueKind = "amount-mismatch"
+	IssueBeneficiaryMismatch ReconciliationIssueKind = "beneficiary-mismatch"
+)
+
+// ReconciliationIssue is a wallet receipt that does not match the mint on Planetmint or the deposit
+// recorded by the service. State is the state of the recorded deposit, empty if none was recorded.
+type ReconciliationIssue struct {
+	Kind                  ReconciliationIssueKind `json:"kind"`
+	LiquidTxID            string                  `json:"liquid-tx-id"`
+	LiquidAddress         string                  `json:"liquid-address"`
+	PlanetmintBeneficiary string                  `json:"planetmint-beneficiary"`
+	State                 ConversionState         `json:"state"`
+	RDDLAmount            uint64                  `json:"rddl-amount"`
+	ExpectedPLMNTAmount   uint64                  `json:"expected-plmnt-amount"`
+	MintedPLMNTAmount     uint64                  `json:"minted-plmnt-amount"`
+	Detail                string                  `json:"detail"`
+}
+
+// ReconciliationReport matches the receipts of the accepted asset by the service wallet within [From, To]
+// against the mints on Planetmint. Receipts that were not minted for a reason recorded by the service are
+// counted in Unminted by the state of their deposit.
+type ReconciliationReport struct {
+	From         int64                   `json:"from"`
+	To           int64                   `json:"to"`
+	CreatedAt    int64                   `json:"created-at"`
+	Receipts     int                     `json:"receipts"`
+	Matched      int                     `json:"matched"`
+	Unminted     map[ConversionState]int `json:"unminted"`
+	ReceivedRDDL uint64                  `json:"received-rddl"`
+	MintedPLMNT  uint64                  `json:"minted-plmnt"`
+	Issues       []ReconciliationIssue   `json:"issues"`
+}
+
+// Formats of the conversion export.
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json"
+)
+
+// ExportedDeposit is a deposit of a conversion as exported for bookkeeping. Confirmations is the number of
+// confirmations the deposit had when it was confirmed for minting and CompletedAt the time it reached its
+// final state. Amounts are in 1e-8 RDDL, whole PLMNT and 1e-8 PLMNT for the remainders, timestamps are
+// unix timestamps.
+type ExportedDeposit struct {
+	ConversionID          string          `json:"conversion-id"`
+	LiquidAddress         string          `json:"liquid-address"`
+	PlanetmintBeneficiary string          `json:"planetmint-beneficiary"`
+	LiquidTxID            string          `json:"liquid-tx-id"`
+	State                 ConversionState `json:"state"`
+	Confirmations         uint64          `json:"confirmations"`
+	RDDLAmount            uint64          `json:"rddl-amount"`
+	ConversionRate        uint64          `json:"conversion-rate"`
+	RemainderCredit       uint64          `json:"remainder-credit"`
+	PLMNTAmount           uint64          `json:"plmnt-amount"`
+	Remainder             uint64          `json:"remainder"`
+	PlanetmintTxHash      string          `json:"planetmint-tx-hash"`
+	RefundTxID            string          `json:"refund-tx-id"`
+	CreatedAt             int64           `json:"created-at"`
+	BroadcastAt           int64           `json:"broadcast-at"`
+	MintedAt              int64           `json:"minted-at"`
+	CompletedAt           int64           `json:"completed-at"`
 }